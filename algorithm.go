@@ -0,0 +1,159 @@
+package rectpack
+
+import (
+	"image"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Packing algorithms selectable through CreateFlags, by name
+var algorithms = map[string]CreateFlags{
+	"split":      0,
+	"guillotine": GuillotineAlgorithm,
+}
+
+// Version of the packing algorithms, bumped whenever a change can move sprites for the same input and configuration
+const AlgorithmVersion = 1
+
+var (
+	splitNames = map[SplitStrategy]string{
+		ShorterAxis:         "shorter-axis",
+		LongerAxis:          "longer-axis",
+		MaximizeLargestRect: "maximize-largest-rect",
+	}
+	findNames = map[FindStrategy]string{
+		FirstFit:         "first-fit",
+		BestAreaFit:      "best-area-fit",
+		BestShortSideFit: "best-short-side-fit",
+		BestLongSideFit:  "best-long-side-fit",
+	}
+)
+
+// Identifies the algorithm and strategies the packer places sprites with, such as "split/shorter-axis/first-fit".
+// It only names the placement code, with AlgorithmVersion it tells whether that code changed; the layout also
+// depends on the sprites, their order and the rest of the config, such as padding, sizes, sorting and rotation.
+func (pack *Packer) AlgorithmID() string {
+	if pack.cfg.CellSize != (image.Point{}) {
+		return "grid"
+	}
+
+	name := "split"
+	for n, flags := range algorithms {
+		if flags != 0 && pack.cfg.Flags&flags == flags {
+			name = n
+		}
+	}
+	return name + "/" + splitNames[pack.cfg.SplitStrategy] + "/" + findNames[pack.cfg.FindStrategy]
+}
+
+// Returns the names of the registered packing algorithms, sorted
+func Algorithms() (names []string) {
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Packs the given sprites with every registered algorithm and reports how each one did, keyed by algorithm name.
+// Use the result to pick the algorithm flag that suits your sprites best.
+func BenchmarkAlgorithms(queued []*image.RGBA) (results map[string]PackResult, err error) {
+	results = make(map[string]PackResult, len(algorithms))
+	for name, flags := range algorithms {
+		pack := NewPacker(PackerCfg{Flags: flags})
+		for id, pic := range queued {
+			pack.Insert(id, pic)
+		}
+		if results[name], err = pack.PackWithResult(); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// Packs like PackWithResult, but keeps trying shuffled insertion orders until the deadline and keeps the smallest atlas.
+// The configured order is always tried first, so a complete packing is returned even if the deadline has already passed.
+func (pack *Packer) PackBestUntil(deadline time.Time) (result PackResult, err error) {
+	if !pack.lock() {
+		return result, ErrAlreadyPacked
+	}
+	defer pack.unlock()
+
+	if pack.packed {
+		return result, ErrAlreadyPacked
+	}
+	if !pack.planned {
+		if err = pack.search(deadline); err != nil {
+			return
+		}
+	}
+	if err = pack.commit(nil); err != nil {
+		return
+	}
+	return pack.result(), nil
+}
+
+// Helper to plan the order that gives the smallest atlas found before the deadline
+func (pack *Packer) search(deadline time.Time) (err error) {
+	var (
+		rng       = rand.New(rand.NewSource(1))
+		order     = append([]queuedData(nil), pack.queued...)
+		best      []queuedData
+		bestFlags CreateFlags
+		bestSkips int
+		bestArea  int
+	)
+	for attempt := 0; attempt == 0 || time.Now().Before(deadline); attempt++ {
+		flags := pack.cfg.Flags
+		if attempt > 0 {
+			rng.Shuffle(len(order), func(i, j int) {
+				order[i], order[j] = order[j], order[i]
+			})
+			flags |= FlagNoSort
+		}
+
+		trial := pack.trial(order, flags)
+		if _, err = trial.Plan(); err != nil {
+			if attempt == 0 {
+				return
+			}
+			continue
+		}
+		skips, size := len(trial.unplaced), area(trial.bounds)
+		if best == nil || skips < bestSkips || (skips == bestSkips && size < bestArea) {
+			best = append(best[:0], order...)
+			bestFlags, bestSkips, bestArea = flags, skips, size
+		}
+	}
+	err = nil
+
+	// planning is deterministic, so replaying the best order gives the same layout
+	flags := pack.cfg.Flags
+	pack.queued, pack.cfg.Flags = best, bestFlags
+	_, err = pack.plan()
+	pack.cfg.Flags = flags
+	return
+}
+
+// Helper to create a packer that plans the given order without touching this packer's state
+func (pack *Packer) trial(order []queuedData, flags CreateFlags) *Packer {
+	trial := NewPacker(pack.cfg)
+	trial.cfg.Flags = flags
+	trial.cfg.OnPlace = nil
+	trial.cfg.OnStep = nil
+	trial.queued = append(trial.queued, order...)
+	for id, size := range pack.sizes {
+		trial.sizes[id] = size
+	}
+	for id, r := range pack.trims {
+		trial.trims[id] = r
+	}
+	for id, rotated := range pack.rotated {
+		trial.rotated[id] = rotated
+	}
+	for id := range pack.pending {
+		trial.pending[id] = true
+	}
+	return trial
+}
@@ -0,0 +1,105 @@
+package rectpack
+
+import (
+	"image"
+	"image/color"
+	"time"
+)
+
+// Builder assembles a PackerCfg through chained calls and creates the packer from it
+type Builder struct {
+	cfg PackerCfg
+}
+
+// Creates a new builder with the default config
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Adds the given flags to the config
+func (b *Builder) Flags(flags CreateFlags) *Builder {
+	b.cfg.Flags |= flags
+	return b
+}
+
+// Lets sprites be rotated 90 degrees when that is the only way they fit, see FlagAllowRotate
+func (b *Builder) AllowRotate() *Builder {
+	b.cfg.Flags |= FlagAllowRotate
+	return b
+}
+
+// Limits the size the atlas may grow to
+func (b *Builder) MaxSize(w, h int) *Builder {
+	b.cfg.MaxSize = image.Pt(w, h)
+	return b
+}
+
+// Sets the size the atlas starts at
+func (b *Builder) InitialSize(w, h int) *Builder {
+	b.cfg.InitialSize = image.Pt(w, h)
+	return b
+}
+
+// Sets the empty pixels kept to the right of and below every sprite
+func (b *Builder) Padding(n int) *Builder {
+	b.cfg.Padding = n
+	return b
+}
+
+// Sets the color the padding is filled with
+func (b *Builder) GutterColor(c color.Color) *Builder {
+	b.cfg.GutterColor = c
+	return b
+}
+
+// Sets the comparator used to order empty spaces
+func (b *Builder) SpaceSort(less SpaceSort) *Builder {
+	b.cfg.SpaceSort = less
+	return b
+}
+
+// Sets the comparator used to order the queued sprites
+func (b *Builder) SortFunc(less SortFunc) *Builder {
+	b.cfg.SortFunc = less
+	return b
+}
+
+// Sets how leftover space is split after placing a sprite
+func (b *Builder) SplitStrategy(strategy SplitStrategy) *Builder {
+	b.cfg.SplitStrategy = strategy
+	return b
+}
+
+// Sets the preferred width to height ratio of the atlas
+func (b *Builder) TargetAspect(aspect float64) *Builder {
+	b.cfg.TargetAspect = aspect
+	return b
+}
+
+// Sets the multiplier applied when the atlas grows
+func (b *Builder) GrowFactor(factor float64) *Builder {
+	b.cfg.GrowFactor = factor
+	return b
+}
+
+// Sets the encoder settings used when saving
+func (b *Builder) Encode(opts EncodeOptions) *Builder {
+	b.cfg.Encode = opts
+	return b
+}
+
+// Sets how often the watch package checks the source files
+func (b *Builder) WatchInterval(interval time.Duration) *Builder {
+	b.cfg.WatchInterval = interval
+	return b
+}
+
+// Returns the config built so far
+func (b *Builder) Config() PackerCfg {
+	return b.cfg
+}
+
+// Creates a new packer with the built config
+func (b *Builder) Build() *Packer {
+	return NewPacker(b.cfg)
+}
@@ -0,0 +1,38 @@
+package rectpack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A failure to load a single file during a batch insert
+type FileError struct {
+	Name string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Every file that failed during a batch insert, sorted by name. It unwraps to the first failure.
+type MultiError []*FileError
+
+func (e MultiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d files failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e MultiError) Unwrap() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e[0]
+}
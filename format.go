@@ -0,0 +1,67 @@
+package rectpack
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encodes an image into w
+type Encoder func(w io.Writer, img image.Image) error
+
+// Decodes an image from r
+type Decoder func(r io.Reader) (image.Image, error)
+
+// Decoders InsertFromFileFormat can use, by format name
+var decoders = map[string]Decoder{
+	"png":  png.Decode,
+	"jpeg": jpeg.Decode,
+	"jpg":  jpeg.Decode,
+	"gif":  gif.Decode,
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]Encoder)
+)
+
+// Registers an encoder that Save uses for filenames ending in ext, such as ".tex".
+// The built in .png, .jpeg and .jpg formats can't be replaced.
+func RegisterFormat(ext string, enc Encoder) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[strings.ToLower(ext)] = enc
+}
+
+// Helper to find the registered encoder for the extension
+func lookupFormat(ext string) (enc Encoder, found bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	enc, found = formats[strings.ToLower(ext)]
+	return
+}
+
+// Helper to decode an image in any format registered with the image package, explaining the usual cause when none match
+func decodeAny(r io.Reader) (img image.Image, err error) {
+	if img, _, err = image.Decode(r); errors.Is(err, image.ErrFormat) {
+		err = fmt.Errorf("%w: rectpack registers %s, other formats need their decoder imported, such as _ \"golang.org/x/image/webp\"", err, decoderNames())
+	}
+	return
+}
+
+// Helper to list the decoder formats rectpack imports, in sorted order
+func decoderNames() string {
+	names := make([]string, 0, len(decoders))
+	for name := range decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
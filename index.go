@@ -0,0 +1,128 @@
+package rectpack
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"sort"
+)
+
+// Flags stored with each sprite in a binary index
+const (
+	IndexRotated uint8 = 1 << iota
+	IndexTrimmed
+)
+
+// A sprite read back from a binary index
+type IndexEntry struct {
+	Id    int
+	Rect  image.Rectangle
+	Flags uint8
+}
+
+type indexHeader struct {
+	W, H, Count uint32
+}
+
+type indexSprite struct {
+	Id         int32
+	X, Y, W, H uint16
+	Flags      uint8
+}
+
+// Saves a compact little-endian table of where every sprite is in the texture, ordered by id, for loaders that can't afford JSON.
+// The table is the atlas' right and bottom edge and sprite count as uint32, then each sprite's id as int32, x, y, w and h as uint16 and its flags as uint8.
+// Positions are absolute, so with an Offset the edges are the offset plus the atlas size, and negative positions fail with ErrIndexOverflow.
+func (pack *Packer) SaveBinaryIndex(filename string) (err error) {
+	if !pack.packed {
+		return ErrNotPacked
+	}
+
+	if pack.bounds.Min.X < 0 || pack.bounds.Min.Y < 0 {
+		return ErrIndexOverflow
+	}
+
+	ids := make([]int, 0, len(pack.rects))
+	for id := range pack.rects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	sprites := make([]indexSprite, 0, len(ids))
+	for _, id := range ids {
+		r := pack.rects[id]
+		if int(int32(id)) != id || r.Min.X < 0 || r.Min.Y < 0 || r.Max.X > 0xffff || r.Max.Y > 0xffff {
+			return ErrIndexOverflow
+		}
+		var flags uint8
+		if pack.rotated[id] {
+			flags |= IndexRotated
+		}
+		if pack.TrimRect(id).Size() != pack.sourceSize(id) {
+			flags |= IndexTrimmed
+		}
+		sprites = append(sprites, indexSprite{
+			Id:    int32(id),
+			X:     uint16(r.Min.X),
+			Y:     uint16(r.Min.Y),
+			W:     uint16(r.Dx()),
+			H:     uint16(r.Dy()),
+			Flags: flags,
+		})
+	}
+
+	return writeAtomic(filename, func(file io.Writer) (err error) {
+		w := bufio.NewWriter(file)
+		if err = binary.Write(w, binary.LittleEndian, indexHeader{W: uint32(pack.bounds.Max.X), H: uint32(pack.bounds.Max.Y), Count: uint32(len(sprites))}); err != nil {
+			return
+		}
+		if err = binary.Write(w, binary.LittleEndian, sprites); err != nil {
+			return
+		}
+		return w.Flush()
+	})
+}
+
+// Loads a table written by SaveBinaryIndex, returning the atlas' right and bottom edge and the sprites ordered by id.
+// A sprite count the file is too short for fails with ErrCorruptIndex before anything is allocated for it.
+func LoadBinaryIndex(filename string) (size image.Point, entries []IndexEntry, err error) {
+	var (
+		file *os.File
+		info os.FileInfo
+	)
+	if file, err = os.Open(filename); err != nil {
+		return
+	}
+	defer file.Close()
+	if info, err = file.Stat(); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(file)
+	var header indexHeader
+	if err = binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return
+	}
+	room := (info.Size() - int64(binary.Size(header))) / int64(binary.Size(indexSprite{}))
+	if int64(header.Count) > room {
+		return size, nil, fmt.Errorf("%w: %d sprites in %d bytes", ErrCorruptIndex, header.Count, info.Size())
+	}
+	sprites := make([]indexSprite, header.Count)
+	if err = binary.Read(r, binary.LittleEndian, sprites); err != nil {
+		return
+	}
+
+	size = image.Pt(int(header.W), int(header.H))
+	entries = make([]IndexEntry, len(sprites))
+	for i, s := range sprites {
+		entries[i] = IndexEntry{
+			Id:    int(s.Id),
+			Rect:  rect(int(s.X), int(s.Y), int(s.W), int(s.H)),
+			Flags: s.Flags,
+		}
+	}
+	return
+}
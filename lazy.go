@@ -0,0 +1,78 @@
+package rectpack
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// image.Image that reads each pixel from the placed sources on demand instead of from a drawn texture.
+// The sprites crossing a row are looked up once per row, so reading row by row is cheap.
+type lazyImage struct {
+	pack   *Packer
+	gutter color.RGBA
+	y      int
+	row    []int
+}
+
+func newLazyImage(pack *Packer) *lazyImage {
+	img := &lazyImage{pack: pack, y: pack.bounds.Min.Y - 1}
+	if pack.cfg.GutterColor != nil {
+		img.gutter = color.RGBAModel.Convert(pack.cfg.GutterColor).(color.RGBA)
+	}
+	return img
+}
+
+func (img *lazyImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img *lazyImage) Bounds() image.Rectangle {
+	return img.pack.bounds
+}
+
+// Helper to get the area a sprite covers in the texture, including its gutter when one is drawn
+func (img *lazyImage) covers(id int) image.Rectangle {
+	r := img.pack.rects[id]
+	if p := img.pack.gutter(); p > 0 {
+		r.Max = r.Max.Add(image.Pt(p, p))
+	}
+	return r
+}
+
+func (img *lazyImage) At(x, y int) color.Color {
+	return img.RGBAAt(x, y)
+}
+
+func (img *lazyImage) RGBAAt(x, y int) color.RGBA {
+	if y != img.y {
+		img.y = y
+		img.row = img.row[:0]
+		for id := range img.pack.rects {
+			if r := img.covers(id); r.Min.Y <= y && y < r.Max.Y {
+				img.row = append(img.row, id)
+			}
+		}
+		sort.Slice(img.row, func(i, j int) bool {
+			return img.pack.rects[img.row[i]].Min.X < img.pack.rects[img.row[j]].Min.X
+		})
+	}
+
+	i := sort.Search(len(img.row), func(i int) bool {
+		return img.covers(img.row[i]).Max.X > x
+	})
+	if i == len(img.row) || img.covers(img.row[i]).Min.X > x {
+		return color.RGBA{}
+	}
+
+	var (
+		id = img.row[i]
+		r  = img.pack.rects[id]
+	)
+	if !image.Pt(x, y).In(r) {
+		return img.gutter
+	}
+	pic := img.pack.images[id]
+	at := img.pack.pixelsAt(id, pic)
+	return pic.RGBAAt(x-at.X+pic.Rect.Min.X, y-at.Y+pic.Rect.Min.Y)
+}
@@ -0,0 +1,55 @@
+package rectpack
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// Packs the sprites of the given packed packers into a new packer, using the first packer's configuration.
+// Sprites are copied out of each texture, so trimming, rotation, origins and metadata carry over as they were.
+// Sprites a packer couldn't place are left out; an id found in more than one packer is an error, as is giving no packers.
+func Merge(others ...*Packer) (merged *Packer, err error) {
+	if len(others) == 0 {
+		return nil, ErrNothingToMerge
+	}
+
+	merged = NewPacker(others[0].cfg)
+	for _, other := range others {
+		if !other.hasPic() {
+			return nil, ErrNotPacked
+		}
+		for _, id := range other.order {
+			r, placed := other.rects[id]
+			if !placed {
+				continue
+			}
+			if _, has := merged.sizes[id]; has {
+				return nil, fmt.Errorf("%w: id %d", ErrDuplicateId, id)
+			}
+
+			pic := image.NewRGBA(image.Rectangle{Max: r.Size()})
+			draw.Draw(pic, pic.Rect, other.pic, r.Min, draw.Src)
+			merged.queued = append(merged.queued, queuedData{id: id, pic: pic})
+			merged.order = append(merged.order, id)
+			merged.sizes[id] = other.sizes[id]
+			if trim, has := other.trims[id]; has {
+				merged.trims[id] = trim
+			}
+			if other.rotated[id] {
+				merged.rotated[id] = true
+			}
+			if origin, has := other.origins[id]; has {
+				merged.origins[id] = origin
+			}
+			if meta, has := other.meta[id]; has {
+				merged.meta[id] = meta
+			}
+		}
+	}
+
+	if err = merged.Pack(); err != nil {
+		return nil, err
+	}
+	return
+}
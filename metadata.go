@@ -0,0 +1,97 @@
+package rectpack
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+	"sort"
+)
+
+type metaSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type metaRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type metaFrame struct {
+	Id         int         `json:"id"`
+	Frame      metaRect    `json:"frame"`
+	Rotated    bool        `json:"rotated"`
+	Trimmed    bool        `json:"trimmed"`
+	SourceSize metaSize    `json:"sourceSize"`
+	SourceRect metaRect    `json:"spriteSourceSize"`
+	Meta       interface{} `json:"meta,omitempty"`
+}
+
+type metadata struct {
+	Algorithm string      `json:"algorithm"`
+	Version   int         `json:"version"`
+	Size      metaSize    `json:"size"`
+	Frames    []metaFrame `json:"frames"`
+}
+
+func toMetaRect(r image.Rectangle) metaRect {
+	return metaRect{X: r.Min.X, Y: r.Min.Y, W: r.Dx(), H: r.Dy()}
+}
+
+// Attaches arbitrary data to a sprite, it is written into the sprite's frame by SaveMetadata and must be JSON marshalable
+func (pack *Packer) SetMeta(id int, v interface{}) {
+	pack.meta[id] = v
+}
+
+// Returns the data attached to a sprite with SetMeta, or nil
+func (pack *Packer) Meta(id int) interface{} {
+	return pack.meta[id]
+}
+
+// Saves a JSON description of where every sprite is in the texture, ordered by id
+func (pack *Packer) SaveMetadata(filename string) (err error) {
+	if !pack.packed {
+		return ErrNotPacked
+	}
+
+	ids := make([]int, 0, len(pack.rects))
+	for id := range pack.rects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	data := metadata{
+		Algorithm: pack.AlgorithmID(),
+		Version:   AlgorithmVersion,
+		Size:      metaSize{W: pack.bounds.Dx(), H: pack.bounds.Dy()},
+		Frames:    make([]metaFrame, 0, len(ids)),
+	}
+	for _, id := range ids {
+		var (
+			source  = pack.SourceSize(id)
+			trimmed = pack.TrimRect(id)
+			rotated = pack.rotated[id]
+		)
+		if rotated {
+			// the trim rect is stored rotated with the sprite, describe it in the upright source instead
+			trimmed = unrotateRect(trimmed, source.Y)
+		}
+		data.Frames = append(data.Frames, metaFrame{
+			Id:         id,
+			Frame:      toMetaRect(pack.rects[id]),
+			Rotated:    rotated,
+			Trimmed:    trimmed.Size() != source,
+			SourceSize: metaSize{W: source.X, H: source.Y},
+			SourceRect: toMetaRect(trimmed),
+			Meta:       pack.meta[id],
+		})
+	}
+
+	return writeAtomic(filename, func(file io.Writer) error {
+		enc := json.NewEncoder(file)
+		enc.SetIndent("", "\t")
+		return enc.Encode(data)
+	})
+}
@@ -1,14 +1,28 @@
 package rectpack
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
+	"io/fs"
+	"math"
 	"os"
 	"path"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
 )
 
 // This texture packer algorithm is based on this project
@@ -22,13 +36,223 @@ var (
 	ErrNotPacked          = errors.New("Packer must be packed")
 	ErrNotFoundNoDefault  = errors.New("Id doesn't exist and a default sprite wasn't specified")
 	ErrAlreadyPacked      = errors.New("Pack has already been called for this packer")
+	ErrNotPlanned         = errors.New("Packer must be planned and not yet committed")
+	ErrBufferTooSmall     = errors.New("Buffer is smaller than the packed texture")
+	ErrInvalidPaletteSize = errors.New("Palette size must be between 1 and 256")
+	ErrSpriteTooLarge     = errors.New("Sprite is larger than the maximum atlas size")
+	ErrMaxSizeExceeded    = errors.New("Packer can't grow past its maximum size")
+	ErrNoGrowth           = errors.New("Packer growth didn't make room for the sprite")
+	ErrSpriteTooWide      = errors.New("Sprite is wider than the fixed atlas width")
+	ErrSpriteTooTall      = errors.New("Sprite is taller than the fixed atlas height")
+	ErrNilImage           = errors.New("Image is nil")
+	ErrDuplicateId        = errors.New("Id has already been inserted")
+	ErrInvalidStride      = errors.New("Stride must be at least 4 times the width")
+	ErrShortPixBuffer     = errors.New("Pixel buffer is smaller than stride times height")
+	ErrSourceNotKept      = errors.New("Source image wasn't kept, see FlagKeepSources")
+	ErrNotPlaceholder     = errors.New("Id wasn't inserted with InsertSize or was already provided")
+	ErrSizeMismatch       = errors.New("Image size doesn't match the inserted size")
+	ErrMissingPixels      = errors.New("Pixels were never provided for a sprite inserted with InsertSize")
+	ErrIndexOverflow      = errors.New("Sprite id or position doesn't fit in the binary index")
+	ErrCorruptIndex       = errors.New("Binary index holds more sprites than the file has room for")
+	ErrNothingToMerge     = errors.New("Merge needs at least one packer")
+	ErrUnknownFormat      = errors.New("Unknown image format")
+	ErrCropOutOfBounds    = errors.New("Crop isn't within the image's bounds")
+	ErrSpriteExceedsCell  = errors.New("Sprite is larger than the grid cell size")
+	ErrNotOpaque          = errors.New("Sprites aren't fully opaque")
+	ErrDroppedSprites     = errors.New("Sprites would be dropped because their ids were inserted more than once")
+	ErrAlphaLostInJPEG    = errors.New("Texture has transparent pixels which jpeg can't store, see FlagAllowAlphaLoss")
 )
 
 type PackFlags uint8
-type CreateFlags uint8
+type CreateFlags uint16
+
+const (
+	// Merges adjacent empty spaces back together after each placement to reduce fragmentation
+	GuillotineAlgorithm CreateFlags = 1 << iota
+	// Stores the saved atlas with premultiplied color values, the in-memory atlas is unaffected.
+	// FormatRGBA is saved as 8 bit and FormatRGBA64 as 16 bit color, FormatGray has no alpha and is saved as is.
+	FlagPremultiplyOnSave
+	// Releases each source image as soon as it has been drawn into the texture, rather than after all of them,
+	// so sources only referenced by the packer can be garbage collected while the rest are drawn
+	FlagStreamBlit
+	// Skips sprites that can't be placed within MaxSize instead of failing, see Unplaced
+	FlagBestEffort
+	// Places sprites in insertion order instead of sorting them first, faster for presorted input but may pack worse otherwise
+	FlagNoSort
+	// Pins the atlas width to InitialSize.X so that it only grows in height
+	FlagFixedWidth
+	// Pins the atlas height to InitialSize.Y so that it only grows in width
+	FlagFixedHeight
+	// Trims transparent borders off of sprites as they are inserted, see TrimAlphaThreshold, TrimColor and TrimRect.
+	// Fully transparent sprites keep their top left pixel
+	FlagTrim
+	// Keeps the source images after packing so that Source can return them, at the cost of holding every
+	// sprite in memory twice. Takes precedence over FlagStreamBlit.
+	FlagKeepSources
+	// Lets Save write a texture with transparent pixels to jpeg, which drops the alpha, instead of returning ErrAlphaLostInJPEG
+	FlagAllowAlphaLoss
+	// Lets Plan rotate a sprite 90 degrees clockwise when it would only fit within MaxSize that way, see IsRotated.
+	// Sprites inserted with InsertAutoRotate are never turned back
+	FlagAllowRotate
+	// Makes Plan fail with ErrDroppedSprites when an id was inserted more than once, instead of the later sprite replacing the earlier
+	FlagStrictCounts
+	// Makes Plan fail with ErrNotOpaque if any sprite has a pixel that isn't fully opaque
+	FlagRequireOpaque
+)
+
+// Orders the packer's empty spaces, returns true if a should be tried before b
+type SpaceSort func(a, b image.Rectangle) bool
+
+// Tries the smallest empty space first so that the tightest fit is found, this is the default
+func SpaceSmallestFirst(a, b image.Rectangle) bool {
+	return area(a) < area(b)
+}
+
+// Tries the largest empty space first to leave bigger leftovers
+func SpaceLargestFirst(a, b image.Rectangle) bool {
+	return area(a) > area(b)
+}
+
+// Tries small, square-ish empty spaces first; long thin spaces are penalized by their aspect ratio
+func SpaceAreaAspect(a, b image.Rectangle) bool {
+	return float64(area(a))*aspect(a) < float64(area(b))*aspect(b)
+}
+
+// Encoder settings used when saving the packer texture, zero values keep each encoder's defaults
+type EncodeOptions struct {
+	PNGCompression png.CompressionLevel
+	// 1 to 100, zero uses jpeg.DefaultQuality
+	JPEGQuality int
+}
+
+// Pixel format of the image returned by Output and written by Save
+type PixelFormat uint8
+
+const (
+	// 8 bit premultiplied color, the format the atlas is packed in, this is the default
+	FormatRGBA PixelFormat = iota
+	// 8 bit color that isn't premultiplied by alpha
+	FormatNRGBA
+	// 8 bit grayscale without alpha
+	FormatGray
+	// 16 bit premultiplied color
+	FormatRGBA64
+)
+
+// Orders the queued sprites before packing, returns true if a should be placed before b
+type SortFunc func(a, b image.Rectangle) bool
+
+// Places the largest sprites by area first, this is the default
+func SortByArea(a, b image.Rectangle) bool {
+	return area(a) > area(b)
+}
+
+// Places the sprites with the longest edge first, ties are broken by area.
+// This tends to pack tighter than SortByArea when sprites have mixed aspect ratios, since long thin
+// sprites are placed while there is still room for them, but may do worse for mostly square sprites.
+func SortByMaxSide(a, b image.Rectangle) bool {
+	if ma, mb := maxSide(a), maxSide(b); ma != mb {
+		return ma > mb
+	}
+	return area(a) > area(b)
+}
+
+// Summary of a finished pack
+type PackResult struct {
+	// Final size of the packer texture
+	Size image.Point
+	// Number of times the texture had to grow
+	Grows int
+	// Fraction of the texture covered by sprites, from 0 to 1
+	Efficiency float64
+	// Whether the texture ended up larger than SoftMaxSize
+	ExceedsSoftMax bool
+}
+
+// Decides how the space left over after placing a sprite is split into new empty spaces
+type SplitStrategy uint8
+
+const (
+	// Cuts along the shorter leftover axis, giving the longer leftover the full side of the space, this is the default
+	ShorterAxis SplitStrategy = iota
+	// Cuts along the longer leftover axis
+	LongerAxis
+	// Cuts whichever way leaves the largest single empty space
+	MaximizeLargestRect
+)
+
+// Decides which of the empty spaces a sprite fits in it is placed into
+type FindStrategy uint8
+
+const (
+	// Takes the first space that fits in SpaceSort order, this is the default
+	FirstFit FindStrategy = iota
+	// Takes the space that leaves the least area over
+	BestAreaFit
+	// Takes the space whose shorter leftover side is the smallest
+	BestShortSideFit
+	// Takes the space whose longer leftover side is the smallest
+	BestLongSideFit
+)
 
 type PackerCfg struct {
 	Flags CreateFlags
+	// Comparator used to order empty spaces, defaults to SpaceSmallestFirst
+	SpaceSort SpaceSort
+	// Largest size the atlas may grow to, a zero component leaves that dimension unbounded
+	MaxSize image.Point
+	// Size above which PackWithResult flags the texture with ExceedsSoftMax, such as a GPU's texture size limit.
+	// Unlike MaxSize it doesn't stop the texture from growing. A zero component isn't checked.
+	SoftMaxSize image.Point
+	Encode      EncodeOptions
+	// How leftover space is split after placing a sprite, defaults to ShorterAxis
+	SplitStrategy SplitStrategy
+	// How the empty space a sprite goes into is chosen, defaults to FirstFit
+	FindStrategy FindStrategy
+	// Comparator used to order the queued sprites, defaults to SortByArea
+	SortFunc SortFunc
+	// Size the atlas starts at before any growth, reduced to MaxSize if it is larger
+	InitialSize image.Point
+	// Pixel format of the image returned by Output and written by Save, defaults to FormatRGBA.
+	// The atlas is always packed as RGBA, other formats are converted from it when Output or Save is called.
+	Format PixelFormat
+	// Number of goroutines the sprites are drawn into the texture with, zero or one draws them serially
+	Parallelism int
+	// Places every sprite, in insertion order, into the next cell of a uniform grid of this size instead of packing them.
+	// Bounds are the whole cell, Padding isn't used and sprites larger than a cell fail with ErrSpriteExceedsCell.
+	CellSize image.Point
+	// With CellSize, centers each sprite in its cell instead of putting it in the top left corner
+	CellCentered bool
+	// Pixel density written into saved pngs, zero leaves it out
+	DPI float64
+	// Size the finished atlas is padded up to with transparent pixels, the sprites aren't moved.
+	// Like growth, padding never changes a fixed side or goes past MaxSize
+	MinSize image.Point
+	// Top left corner of the atlas, for packing into a region of a larger texture; every sprite's bounds are offset by it
+	Offset image.Point
+	// Preferred width to height ratio, when set growth widens or heightens the atlas to stay close to it
+	TargetAspect float64
+	// Multiplier applied to each growing dimension so that fewer grows are needed, zero grows by the sprite size only
+	GrowFactor float64
+	// With FlagTrim, pixels with an alpha at or below this are treated as transparent
+	TrimAlphaThreshold uint8
+	// With FlagTrim, pixels of exactly this color are also treated as transparent, for sprites with a solid matte
+	TrimColor color.Color
+	// Empty pixels kept to the right of and below every sprite
+	Padding int
+	// Minimum number of empty pixels between any two sprites, the larger of this and Padding is reserved
+	MinSeparation int
+	// Color the padding around each sprite is filled with, nil leaves it transparent. Not painted with CellSize
+	GutterColor color.Color
+	// How often the watch package checks the source files for changes, defaults to one second
+	WatchInterval time.Duration
+	// Called with the empty space each sprite was placed into and the sprite's resulting bounds, for debugging.
+	// Sprites are placed again every time the texture grows, so it can be called more than once per id.
+	OnPlace func(id int, space, placed image.Rectangle)
+	// Called after each sprite is placed with its index in placement order and how many empty spaces are left, for profiling
+	OnStep func(spriteIndex, emptySpaceCount int)
+	// Makes the accessors that would panic return zero values instead, the first such error is kept for Err
+	StrictErrors bool
 }
 
 type Packer struct {
@@ -39,13 +263,34 @@ type Packer struct {
 	rects       map[int]image.Rectangle
 	images      map[int]*image.RGBA
 	pic         *image.RGBA
+	free        int
+	grows       int
+	unplaced    []int
+	sizes       map[int]image.Point
+	trims       map[int]image.Rectangle
+	order       []int
+	meta        map[int]interface{}
+	rotated     map[int]bool
+	files       map[int]SourceFile
+	pending     map[int]bool
+	origins     map[int]image.Point
 	nfId        int
+	err         error
+	busy        int32
+	planned     bool
 	packed      bool
 }
 
 // Creates a new packer instance
 func NewPacker(cfg PackerCfg) (pack *Packer) {
-	bounds := rect(0, 0, 0, 0)
+	size := cfg.InitialSize
+	if max := cfg.MaxSize; max.X > 0 && size.X > max.X {
+		size.X = max.X
+	}
+	if max := cfg.MaxSize; max.Y > 0 && size.Y > max.Y {
+		size.Y = max.Y
+	}
+	bounds := rect(cfg.Offset.X, cfg.Offset.Y, size.X, size.Y)
 	pack = &Packer{
 		cfg:         cfg,
 		bounds:      bounds,
@@ -53,30 +298,281 @@ func NewPacker(cfg PackerCfg) (pack *Packer) {
 		rects:       make(map[int]image.Rectangle),
 		images:      make(map[int]*image.RGBA),
 		queued:      make([]queuedData, 0),
+		sizes:       make(map[int]image.Point),
+		trims:       make(map[int]image.Rectangle),
+		meta:        make(map[int]interface{}),
+		rotated:     make(map[int]bool),
+		files:       make(map[int]SourceFile),
+		pending:     make(map[int]bool),
+		origins:     make(map[int]image.Point),
 		nfId:        -1,
 	}
+	if !bounds.Empty() {
+		pack.emptySpaces = append(pack.emptySpaces, bounds)
+		pack.free = area(bounds)
+	}
 	return
 }
 
 // Inserts PictureData into the packer
+//		A nil picture is reported by Pack as ErrNilImage.
 func (pack *Packer) Insert(id int, pic *image.RGBA) {
+	if pic != nil {
+		pack.sizes[id] = pic.Bounds().Size()
+		if pack.cfg.Flags&FlagTrim != 0 {
+			var matte *color.RGBA
+			if pack.cfg.TrimColor != nil {
+				c := color.RGBAModel.Convert(pack.cfg.TrimColor).(color.RGBA)
+				matte = &c
+			}
+			kept := opaqueBounds(pic, pack.cfg.TrimAlphaThreshold, matte)
+			pack.trims[id] = kept.Sub(pic.Rect.Min)
+			pic = pic.SubImage(kept).(*image.RGBA)
+		}
+	}
 	pack.queued = append(pack.queued, queuedData{id: id, pic: pic})
+	pack.order = append(pack.order, id)
+}
+
+// Inserts a w by h sprite filled with c, such as a white pixel or a flat UI fill
+func (pack *Packer) InsertColor(id int, w, h int, c color.Color) {
+	pic := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(pic, pic.Rect, image.NewUniform(c), image.Point{}, draw.Src)
+	pack.Insert(id, pic)
+}
+
+// Inserts a placeholder of the given size that is packed like any other sprite, its pixels must be given
+// to Provide before the texture is drawn. Placeholders aren't trimmed.
+func (pack *Packer) InsertSize(id int, w, h int) {
+	pack.sizes[id] = image.Pt(w, h)
+	pack.pending[id] = true
+	pack.queued = append(pack.queued, queuedData{id: id, pic: &image.RGBA{Rect: image.Rect(0, 0, w, h)}})
+	pack.order = append(pack.order, id)
+}
+
+// Supplies the pixels for a placeholder inserted with InsertSize, pic must be the inserted size.
+// It can be called any time before Commit, including between Plan and Commit.
+func (pack *Packer) Provide(id int, pic *image.RGBA) error {
+	if pack.packed {
+		return ErrAlreadyPacked
+	}
+	if !pack.pending[id] {
+		return fmt.Errorf("%w: id %d", ErrNotPlaceholder, id)
+	}
+	if pic == nil {
+		return fmt.Errorf("%w: id %d", ErrNilImage, id)
+	}
+	if pic.Rect.Size() != pack.sizes[id] {
+		return fmt.Errorf("%w: id %d", ErrSizeMismatch, id)
+	}
+
+	for i := range pack.queued {
+		if pack.queued[i].id == id {
+			pack.queued[i].pic = pic
+		}
+	}
+	if _, has := pack.images[id]; has {
+		pack.images[id] = pic
+	}
+	delete(pack.pending, id)
+	return nil
+}
+
+// Inserts all of the pictures into the packer, in id order.
+// Nothing is inserted if any picture is nil or any id has already been inserted.
+func (pack *Packer) InsertBatch(items map[int]*image.RGBA) (err error) {
+	ids := make([]int, 0, len(items))
+	for id, pic := range items {
+		if pic == nil {
+			return fmt.Errorf("%w: id %d", ErrNilImage, id)
+		}
+		if _, has := pack.sizes[id]; has {
+			return fmt.Errorf("%w: id %d", ErrDuplicateId, id)
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		pack.Insert(id, items[id])
+	}
+	return
+}
+
+// Inserts a copy of raw RGBA pixel bytes into the packer, rows are stride bytes apart
+func (pack *Packer) InsertRaw(id int, pix []byte, w, h, stride int) (err error) {
+	if w < 0 || h < 0 || stride < 4*w {
+		return fmt.Errorf("%w: id %d", ErrInvalidStride, id)
+	}
+	if len(pix) < stride*h {
+		return fmt.Errorf("%w: id %d", ErrShortPixBuffer, id)
+	}
+
+	pic := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		copy(pic.Pix[y*pic.Stride:(y+1)*pic.Stride], pix[y*stride:])
+	}
+	pack.Insert(id, pic)
+	return
+}
+
+// Inserts a copy of the crop region of the image into the packer, the crop must be within the image's bounds
+func (pack *Packer) InsertCrop(id int, img image.Image, crop image.Rectangle) (err error) {
+	if img == nil {
+		return fmt.Errorf("%w: id %d", ErrNilImage, id)
+	}
+	if crop.Empty() || !crop.In(img.Bounds()) {
+		return fmt.Errorf("%w: id %d", ErrCropOutOfBounds, id)
+	}
+
+	pic := image.NewRGBA(image.Rectangle{Max: crop.Size()})
+	draw.Draw(pic, pic.Rect, img, crop.Min, draw.Src)
+	pack.Insert(id, pic)
+	return
+}
+
+// Inserts the picture into the packer, first scaling it down so that its longest side is at most maxSide
+func (pack *Packer) InsertScaled(id int, pic *image.RGBA, maxSide int) {
+	if pic == nil {
+		pack.Insert(id, nil)
+		return
+	}
+
+	r := pic.Bounds()
+	long := r.Dx()
+	if r.Dy() > long {
+		long = r.Dy()
+	}
+	if long <= maxSide {
+		pack.Insert(id, pic)
+		return
+	}
+
+	w := int(math.Round(float64(r.Dx()*maxSide) / float64(long)))
+	h := int(math.Round(float64(r.Dy()*maxSide) / float64(long)))
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), pic, r, draw.Src, nil)
+	pack.Insert(id, scaled)
+}
+
+// Inserts the picture into the packer, first rotating it 90 degrees clockwise if it is taller than it is wide.
+// Use IsRotated to check whether a sprite was rotated.
+func (pack *Packer) InsertAutoRotate(id int, pic *image.RGBA) {
+	if pic != nil && pic.Rect.Dy() > pic.Rect.Dx() {
+		pack.Insert(id, rotate(pic))
+		pack.rotated[id] = true
+		return
+	}
+	pack.Insert(id, pic)
+	delete(pack.rotated, id)
+}
+
+// Inserts a mirrored copy of the picture into the packer
+func (pack *Packer) InsertFlipped(id int, pic *image.RGBA, flipH, flipV bool) {
+	if pic != nil {
+		pic = flip(pic, flipH, flipV)
+	}
+	pack.Insert(id, pic)
 }
 
 // Automatically parse and insert image from file.
 func (pack *Packer) InsertFromFile(id int, filename string) (err error) {
-	var (
-		file *os.File
-		img  image.Image
-		rgba *image.RGBA
-	)
+	return pack.decodeFromFile(id, filename, decodeAny)
+}
+
+// Parses the file with the decoder for the named format, such as "png", and inserts it.
+// Unlike InsertFromFile this doesn't depend on which decoders are registered with the image package.
+func (pack *Packer) InsertFromFileFormat(id int, filename, format string) (err error) {
+	dec, found := decoders[strings.ToLower(format)]
+	if !found {
+		return fmt.Errorf("%w: %q, expected one of %s", ErrUnknownFormat, format, decoderNames())
+	}
 
+	return pack.decodeFromFile(id, filename, dec)
+}
+
+// Automatically parse and insert image from a file in the given file system, such as an embed.FS.
+func (pack *Packer) InsertFromFS(id int, fsys fs.FS, name string) (err error) {
+	return pack.decodeFromFS(id, fsys, name, decodeAny)
+}
+
+// A file on disk a sprite was inserted from, see SourceFiles
+type SourceFile struct {
+	Filename string
+	// Modification time of the file just before it was read
+	ModTime time.Time
+	// decoder the file was read with, so Reload reads it the same way
+	decode Decoder
+}
+
+// Returns the files inserted with InsertFromFile and InsertFromFileFormat, by id
+func (pack *Packer) SourceFiles() map[int]SourceFile {
+	files := make(map[int]SourceFile, len(pack.files))
+	for id, file := range pack.files {
+		files[id] = file
+	}
+	return files
+}
+
+// Reads every file returned by SourceFiles again, with the decoder it was first read with, into a new packer
+// with the same config and default id and packs it. Sprites added from memory aren't carried over.
+func (pack *Packer) Reload() (reloaded *Packer, err error) {
+	reloaded = NewPacker(pack.cfg)
+	reloaded.nfId = pack.nfId
+	for id, file := range pack.files {
+		if err = reloaded.decodeFromFile(id, file.Filename, file.decode); err != nil {
+			return
+		}
+	}
+	err = reloaded.Pack()
+	return
+}
+
+// Returns the config the packer was created with
+func (pack *Packer) Config() PackerCfg {
+	return pack.cfg
+}
+
+// Helper to decode a file from disk and insert it, remembering the file for Reload
+func (pack *Packer) decodeFromFile(id int, filename string, decode Decoder) (err error) {
+	var file *os.File
 	if file, err = os.Open(filename); err != nil {
+		return
+	}
+	defer file.Close()
+
+	// stat before reading so that a change made while reading is still picked up by the watch package
+	source := SourceFile{Filename: filename, decode: decode}
+	if info, err := file.Stat(); err == nil {
+		source.ModTime = info.ModTime()
+	}
+
+	if err = pack.decode(id, file, decode); err != nil {
+		return
+	}
+	pack.files[id] = source
+
+	return
+}
+
+// Helper to decode a file from the file system and insert it
+func (pack *Packer) decodeFromFS(id int, fsys fs.FS, name string, decode Decoder) (err error) {
+	var file fs.File
+	if file, err = fsys.Open(name); err != nil {
 		return err
 	}
 	defer file.Close()
 
-	if img, _, err = image.Decode(file); err != nil {
+	return pack.decode(id, file, decode)
+}
+
+// Helper to decode an image and insert it as RGBA
+func (pack *Packer) decode(id int, r io.Reader, decode Decoder) (err error) {
+	var (
+		img  image.Image
+		rgba *image.RGBA
+	)
+	if img, err = decode(r); err != nil {
 		return err
 	}
 
@@ -94,12 +590,151 @@ func (pack *Packer) InsertFromFile(id int, filename string) (err error) {
 	return
 }
 
-// Helper to find the smallest empty space that'll fit the given bounds
-func (pack Packer) find(bounds image.Rectangle) (index int, found bool) {
+// Inserts every image file under root in the given file system, ids are chosen by idFromName from each file's path.
+// Files that aren't in a registered image format are skipped. Files that fail to load don't stop the others
+// from being inserted, they are returned together as a MultiError.
+func (pack *Packer) InsertAllFromFS(fsys fs.FS, root string, idFromName func(string) int) (err error) {
+	var failed MultiError
+	err = fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err = pack.InsertFromFS(idFromName(name), fsys, name); err != nil && !errors.Is(err, image.ErrFormat) {
+			failed = append(failed, &FileError{Name: name, Err: err})
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool {
+			return failed[i].Name < failed[j].Name
+		})
+		return failed
+	}
+	return nil
+}
+
+// Helper to find the ids that were queued more than once, in insertion order
+func (pack *Packer) duplicates() (ids []int) {
+	seen := make(map[int]int, len(pack.queued))
+	for _, data := range pack.queued {
+		if seen[data.id]++; seen[data.id] == 2 {
+			ids = append(ids, data.id)
+		}
+	}
+	return
+}
+
+// Helper to check that a sprite fits within the configured size limits
+func (pack *Packer) check(data queuedData) error {
+	size := pack.footprint(data.pic).Size()
+	if pack.cfg.Flags&FlagFixedWidth != 0 && size.X > pack.bounds.Dx() {
+		return fmt.Errorf("%w: id %d", ErrSpriteTooWide, data.id)
+	}
+	if pack.cfg.Flags&FlagFixedHeight != 0 && size.Y > pack.bounds.Dy() {
+		return fmt.Errorf("%w: id %d", ErrSpriteTooTall, data.id)
+	}
+	if max := pack.cfg.MaxSize; (max.X > 0 && size.X > max.X) || (max.Y > 0 && size.Y > max.Y) {
+		return fmt.Errorf("%w: id %d", ErrSpriteTooLarge, data.id)
+	}
+	return nil
+}
+
+// Helper to check whether the queued sprite may be rotated to fit within MaxSize.
+// Sprites that are already rotated, such as by InsertAutoRotate, stay the way they were inserted.
+func (pack *Packer) rotatable(data queuedData) bool {
+	return pack.cfg.Flags&FlagAllowRotate != 0 && pack.cfg.MaxSize != (image.Point{}) && !pack.pending[data.id] && !pack.rotated[data.id]
+}
+
+// Helper to report the first placed placeholder whose pixels haven't been provided
+func (pack *Packer) missing() error {
+	for _, id := range pack.order {
+		if _, placed := pack.images[id]; placed && pack.pending[id] {
+			return fmt.Errorf("%w: id %d", ErrMissingPixels, id)
+		}
+	}
+	return nil
+}
+
+// Helper to get the width of the gutter painted to the right of and below every sprite, zero when none is painted.
+// Grid cells are adjacent, so a gutter there would paint over the next cell.
+func (pack *Packer) gutter() int {
+	if pack.cfg.GutterColor == nil || pack.cfg.CellSize != (image.Point{}) {
+		return 0
+	}
+	return pack.cfg.Padding
+}
+
+// Helper to rotate a queued sprite 90 degrees clockwise, or counter clockwise back upright if it was already rotated
+func (pack *Packer) turn(i int) {
+	data := &pack.queued[i]
+	size := pack.sizes[data.id]
+	if pack.rotated[data.id] {
+		if trim, has := pack.trims[data.id]; has {
+			pack.trims[data.id] = unrotateRect(trim, size.X)
+		}
+		data.pic = unrotate(data.pic)
+	} else {
+		if trim, has := pack.trims[data.id]; has {
+			pack.trims[data.id] = image.Rect(size.Y-trim.Max.Y, trim.Min.X, size.Y-trim.Min.Y, trim.Max.X)
+		}
+		data.pic = rotate(data.pic)
+	}
+	pack.sizes[data.id] = image.Pt(size.Y, size.X)
+	pack.rotated[data.id] = !pack.rotated[data.id]
+}
+
+// Helper to get the space a sprite takes up in the texture, including its padding
+func (pack *Packer) footprint(pic *image.RGBA) image.Rectangle {
+	m := pack.margin()
+	return rect(0, 0, pic.Rect.Dx()+m, pic.Rect.Dy()+m)
+}
+
+// Helper to get the space reserved to the right of and below every sprite
+func (pack *Packer) margin() int {
+	if pack.cfg.MinSeparation > pack.cfg.Padding {
+		return pack.cfg.MinSeparation
+	}
+	return pack.cfg.Padding
+}
+
+// Helper to drop a queued sprite that can't be placed and remember its id
+func (pack *Packer) skip(i int) {
+	pack.unplaced = append(pack.unplaced, pack.queued[i].id)
+	pack.queued = append(pack.queued[:i], pack.queued[i+1:]...)
+}
+
+// Helper to find the empty space that'll fit the given bounds according to the find strategy, ties go to the earlier space
+func (pack *Packer) find(bounds image.Rectangle) (index int, found bool) {
+	best := 0
 	for i, space := range pack.emptySpaces {
-		if bounds.Dx() <= space.Dx() && bounds.Dy() <= space.Dy() {
+		dw, dh := space.Dx()-bounds.Dx(), space.Dy()-bounds.Dy()
+		if dw < 0 || dh < 0 {
+			continue
+		}
+
+		var score int
+		switch pack.cfg.FindStrategy {
+		case BestAreaFit:
+			score = area(space) - area(bounds)
+		case BestShortSideFit:
+			score = dw
+			if dh < dw {
+				score = dh
+			}
+		case BestLongSideFit:
+			score = dw
+			if dh > dw {
+				score = dh
+			}
+		default:
 			return i, true
 		}
+		if !found || score < best {
+			index, found, best = i, true, score
+		}
 	}
 	return
 }
@@ -111,26 +746,217 @@ func (pack *Packer) remove(i int) (removed image.Rectangle) {
 	return
 }
 
+// Helper to get the comparator used to order the empty spaces.
+// Spaces the comparator considers equal are ordered top to bottom, then left to right, so the layout is deterministic.
+func (pack *Packer) spaceLess() SpaceSort {
+	less := pack.cfg.SpaceSort
+	if less == nil {
+		less = SpaceSmallestFirst
+	}
+	return func(a, b image.Rectangle) bool {
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		if a.Min.Y != b.Min.Y {
+			return a.Min.Y < b.Min.Y
+		}
+		return a.Min.X < b.Min.X
+	}
+}
+
+// Helper to add an empty space at its sorted position, avoiding a full sort for every insert
+func (pack *Packer) push(space image.Rectangle) {
+	less := pack.spaceLess()
+	i := sort.Search(len(pack.emptySpaces), func(i int) bool {
+		return less(space, pack.emptySpaces[i])
+	})
+	pack.emptySpaces = append(pack.emptySpaces, image.Rectangle{})
+	copy(pack.emptySpaces[i+1:], pack.emptySpaces[i:])
+	pack.emptySpaces[i] = space
+}
+
+// Helper to merge empty spaces that share a full edge until no more merges are possible
+func (pack *Packer) merge() {
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(pack.emptySpaces) && !merged; i++ {
+			for j := i + 1; j < len(pack.emptySpaces); j++ {
+				if r, ok := join(pack.emptySpaces[i], pack.emptySpaces[j]); ok {
+					pack.remove(j)
+					pack.remove(i)
+					pack.push(r)
+					merged = true
+					break
+				}
+			}
+		}
+	}
+}
+
 // Helper to increase the size of the internal texture and readd the queued textures to keep it defragmented
 func (pack *Packer) grow(growBy image.Point, endex int) (err error) {
 	newSize := pack.bounds.Size().Add(growBy)
-	pack.bounds = rect(pack.bounds.Min.X, pack.bounds.Min.Y, newSize.X, newSize.Y)
-	pack.emptySpaces = []image.Rectangle{pack.bounds}
+	if target := pack.cfg.TargetAspect; target > 0 {
+		newSize = growTowards(pack.bounds.Size(), growBy, target, pack.limit)
+	}
+	if factor := pack.cfg.GrowFactor; factor > 0 {
+		size := pack.bounds.Size()
+		if scaled := int(float64(size.X) * factor); newSize.X > size.X && scaled > newSize.X {
+			newSize.X = scaled
+		}
+		if scaled := int(float64(size.Y) * factor); newSize.Y > size.Y && scaled > newSize.Y {
+			newSize.Y = scaled
+		}
+	}
+	newSize = pack.limit(newSize)
+	pack.grows++
+
+	for {
+		if err = pack.refill(newSize, endex); !errors.Is(err, ErrGrowthFailed) {
+			return
+		}
+		// growing a single axis can leave the placed sprites unable to fit back in,
+		// so keep growing both axes until they do or the limits stop it
+		larger := pack.limit(newSize.Add(growBy))
+		if larger == newSize {
+			return
+		}
+		newSize = larger
+	}
+}
+
+// Helper to resize the internal texture and readd the first endex queued textures into it
+func (pack *Packer) refill(size image.Point, endex int) (err error) {
+	pack.bounds = rect(pack.bounds.Min.X, pack.bounds.Min.Y, size.X, size.Y)
+	// reuse the backing array, it will be needed again as the queued textures are readded
+	pack.emptySpaces = append(pack.emptySpaces[:0], pack.bounds)
+	pack.free = area(pack.bounds)
 
 	for _, data := range pack.queued[0:endex] {
 		if err = pack.insert(data); err != nil {
 			return
 		}
 	}
-
 	return
 }
 
+// Helper to keep a grown size within the fixed dimensions and MaxSize
+func (pack *Packer) limit(size image.Point) image.Point {
+	if pack.cfg.Flags&FlagFixedWidth != 0 {
+		size.X = pack.bounds.Dx()
+	}
+	if pack.cfg.Flags&FlagFixedHeight != 0 {
+		size.Y = pack.bounds.Dy()
+	}
+	if max := pack.cfg.MaxSize; max.X > 0 && size.X > max.X {
+		size.X = max.X
+	}
+	if max := pack.cfg.MaxSize; max.Y > 0 && size.Y > max.Y {
+		size.Y = max.Y
+	}
+	return size
+}
+
+// Helper to place the queued sprites, in insertion order, into the cells of a uniform grid that is about as wide as it is tall
+func (pack *Packer) planGrid() error {
+	var (
+		cell       = pack.cfg.CellSize
+		max        = pack.cfg.MaxSize
+		bestEffort = pack.cfg.Flags&FlagBestEffort != 0
+	)
+	for i := 0; i < len(pack.queued); i++ {
+		if r := pack.queued[i].pic.Rect; r.Dx() > cell.X || r.Dy() > cell.Y {
+			if !bestEffort {
+				return fmt.Errorf("%w: id %d", ErrSpriteExceedsCell, pack.queued[i].id)
+			}
+			pack.skip(i)
+			i--
+		}
+	}
+	if len(pack.queued) == 0 {
+		return nil
+	}
+
+	cols := int(math.Ceil(math.Sqrt(float64(len(pack.queued)))))
+	if max.X > 0 && cols > max.X/cell.X {
+		cols = max.X / cell.X
+	}
+	rows := 0
+	if cols > 0 {
+		rows = (len(pack.queued) + cols - 1) / cols
+	}
+	if max.Y > 0 && rows > max.Y/cell.Y {
+		rows = max.Y / cell.Y
+	}
+	for len(pack.queued) > cols*rows {
+		if !bestEffort {
+			return fmt.Errorf("%w: id %d", ErrMaxSizeExceeded, pack.queued[cols*rows].id)
+		}
+		pack.skip(cols * rows)
+	}
+
+	pack.bounds = rect(pack.bounds.Min.X, pack.bounds.Min.Y, cols*cell.X, rows*cell.Y)
+	pack.emptySpaces = pack.emptySpaces[:0]
+	pack.free = 0
+	for i, data := range pack.queued {
+		at := pack.bounds.Min.Add(image.Pt(i%cols*cell.X, i/cols*cell.Y))
+		pack.rects[data.id] = image.Rectangle{Min: at, Max: at.Add(cell)}
+		pack.images[data.id] = data.pic
+		if pack.cfg.OnPlace != nil {
+			pack.cfg.OnPlace(data.id, pack.rects[data.id], pack.rects[data.id])
+		}
+		if pack.cfg.OnStep != nil {
+			pack.cfg.OnStep(i, 0)
+		}
+	}
+	return nil
+}
+
+// Helper to get where a sprite's pixels start in the texture, the corner of its bounds unless it's centered in a grid cell
+func (pack *Packer) pixelsAt(id int, pic *image.RGBA) image.Point {
+	r := pack.rects[id]
+	if pack.cfg.CellSize != (image.Point{}) && pack.cfg.CellCentered {
+		return r.Min.Add(r.Size().Sub(pic.Rect.Size()).Div(2))
+	}
+	return r.Min
+}
+
+// Helper to extend the texture to MinSize, leaving the placed sprites where they are
+func (pack *Packer) pad() {
+	var (
+		old  = pack.bounds
+		size = old.Size()
+	)
+	if min := pack.cfg.MinSize; size.X < min.X {
+		size.X = min.X
+	}
+	if min := pack.cfg.MinSize; size.Y < min.Y {
+		size.Y = min.Y
+	}
+	if size = pack.limit(size); size == old.Size() {
+		return
+	}
+
+	pack.bounds = rect(old.Min.X, old.Min.Y, size.X, size.Y)
+	for _, space := range []image.Rectangle{
+		image.Rect(old.Max.X, old.Min.Y, pack.bounds.Max.X, pack.bounds.Max.Y),
+		image.Rect(old.Min.X, old.Max.Y, old.Max.X, pack.bounds.Max.Y),
+	} {
+		if !space.Empty() {
+			pack.push(space)
+			pack.free += area(space)
+		}
+	}
+}
+
 // Helper to segment a found space so that the given data can fit in what's left
 func (pack *Packer) insert(data queuedData) (err error) {
 	var (
 		s            *createdSplits
-		bounds       = data.pic.Bounds()
+		bounds       = pack.footprint(data.pic)
 		index, found = pack.find(bounds)
 	)
 
@@ -139,105 +965,523 @@ func (pack *Packer) insert(data queuedData) (err error) {
 	}
 
 	space := pack.remove(index)
-	if s, err = split(bounds, space); err != nil {
+	if s, err = split(bounds, space, pack.cfg.SplitStrategy); err != nil {
 		return
 	}
 
 	if s.hasBig {
-		pack.emptySpaces = append(pack.emptySpaces, s.bigger)
+		pack.push(s.bigger)
 	}
 	if s.hasSmall {
-		pack.emptySpaces = append(pack.emptySpaces, s.smaller)
+		pack.push(s.smaller)
+	}
+	if pack.cfg.Flags&GuillotineAlgorithm != 0 {
+		pack.merge()
 	}
 
-	sort.Slice(pack.emptySpaces, func(i, j int) bool {
-		return area(pack.emptySpaces[i]) < area(pack.emptySpaces[j])
-	})
-
-	pack.rects[data.id] = rect(space.Min.X, space.Min.Y, bounds.Dx(), bounds.Dy())
+	pack.free -= area(bounds)
+	pack.rects[data.id] = rect(space.Min.X, space.Min.Y, data.pic.Rect.Dx(), data.pic.Rect.Dy())
 	pack.images[data.id] = data.pic
+	if pack.cfg.OnPlace != nil {
+		pack.cfg.OnPlace(data.id, space, pack.rects[data.id])
+	}
 	return
 }
 
 // Pack takes the added textures and packs them into the packer texture, growing the texture if necessary.
+// Concurrent calls are safe, all but the first fail with ErrAlreadyPacked.
 func (pack *Packer) Pack() (err error) {
-	if pack.packed {
+	if !pack.lock() {
 		return ErrAlreadyPacked
 	}
+	defer pack.unlock()
 
-	// sort queued images largest to smallest
-	sort.Slice(pack.queued, func(i, j int) bool {
-		return area(pack.queued[i].pic.Bounds()) > area(pack.queued[j].pic.Bounds())
-	})
+	if _, err = pack.plan(); err != nil {
+		return
+	}
+	return pack.commit(nil)
+}
 
-	for i, data := range pack.queued {
-		var (
-			bounds   = data.pic.Bounds()
-			_, found = pack.find(bounds)
+// Helper to claim the packer while packing, returns false if a Pack, Plan or Commit is already running
+func (pack *Packer) lock() bool {
+	return atomic.CompareAndSwapInt32(&pack.busy, 0, 1)
+}
+
+// Helper to release the packer once packing is done
+func (pack *Packer) unlock() {
+	atomic.StoreInt32(&pack.busy, 0)
+}
+
+// PackAndSlice packs like Pack and returns every placed sprite's SubImage, by id.
+// The subimages are views into the packed image, see SubImage.
+func (pack *Packer) PackAndSlice() (sprites map[int]*image.RGBA, err error) {
+	if err = pack.Pack(); err != nil {
+		return
+	}
+
+	sprites = make(map[int]*image.RGBA, len(pack.rects))
+	for id := range pack.rects {
+		sprites[id] = pack.SubImage(id)
+	}
+	return
+}
+
+// PackWithResult packs like Pack and also reports how the packing went.
+func (pack *Packer) PackWithResult() (result PackResult, err error) {
+	if err = pack.Pack(); err != nil {
+		return
+	}
+	return pack.result(), nil
+}
+
+// Helper to summarize a finished pack
+func (pack *Packer) result() (result PackResult) {
+	used := 0
+	for _, r := range pack.rects {
+		used += area(r)
+	}
+	result = PackResult{
+		Size:  pack.bounds.Size(),
+		Grows: pack.grows,
+	}
+	if total := area(pack.bounds); total > 0 {
+		result.Efficiency = float64(used) / float64(total)
+	}
+	if soft := pack.cfg.SoftMaxSize; (soft.X > 0 && result.Size.X > soft.X) || (soft.Y > 0 && result.Size.Y > soft.Y) {
+		result.ExceedsSoftMax = true
+	}
+	return
+}
+
+// Plan places the added textures and returns the final texture size without allocating the texture.
+// Call Commit afterwards to allocate and draw the texture.
+func (pack *Packer) Plan() (size image.Point, err error) {
+	if !pack.lock() {
+		return size, ErrAlreadyPacked
+	}
+	defer pack.unlock()
+	return pack.plan()
+}
+
+// Helper to place the added textures, see Plan
+func (pack *Packer) plan() (size image.Point, err error) {
+	if pack.packed {
+		return size, ErrAlreadyPacked
+	}
+	if pack.planned {
+		return pack.bounds.Size(), nil
+	}
+
+	for _, data := range pack.queued {
+		if data.pic == nil {
+			return size, fmt.Errorf("%w: id %d", ErrNilImage, data.id)
+		}
+	}
+	if pack.cfg.Flags&FlagStrictCounts != 0 {
+		if dropped := pack.duplicates(); len(dropped) > 0 {
+			return size, fmt.Errorf("%w: ids %v", ErrDroppedSprites, dropped)
+		}
+	}
+	if pack.cfg.Flags&FlagRequireOpaque != 0 {
+		var ids []int
+		for _, data := range pack.queued {
+			if !pack.pending[data.id] && !data.pic.Opaque() {
+				ids = append(ids, data.id)
+			}
+		}
+		if len(ids) > 0 {
+			return size, fmt.Errorf("%w: ids %v", ErrNotOpaque, ids)
+		}
+	}
+
+	bestEffort := pack.cfg.Flags&FlagBestEffort != 0
+
+	for i := 0; i < len(pack.queued); i++ {
+		if err = pack.check(pack.queued[i]); err != nil && pack.rotatable(pack.queued[i]) {
+			size := pack.queued[i].pic.Rect.Size()
+			if pack.check(queuedData{id: pack.queued[i].id, pic: &image.RGBA{Rect: rect(0, 0, size.Y, size.X)}}) == nil {
+				pack.turn(i)
+				err = nil
+			}
+		}
+		if err != nil {
+			if !bestEffort {
+				return
+			}
+			err = nil
+			pack.skip(i)
+			i--
+		}
+	}
+
+	if pack.cfg.CellSize != (image.Point{}) {
+		if err = pack.planGrid(); err != nil {
+			return
+		}
+		pack.pad()
+		pack.planned = true
+		return pack.bounds.Size(), nil
+	}
+
+	// sort queued images largest to smallest
+	if pack.cfg.Flags&FlagNoSort == 0 {
+		less := pack.cfg.SortFunc
+		if less == nil {
+			less = SortByArea
+		}
+		sort.SliceStable(pack.queued, func(i, j int) bool {
+			return less(pack.queued[i].pic.Bounds(), pack.queued[j].pic.Bounds())
+		})
+	}
+
+	for i := 0; i < len(pack.queued); i++ {
+		var (
+			data   = pack.queued[i]
+			bounds = pack.footprint(data.pic)
+			found  bool
+			turned bool
 		)
 
-		if !found {
+		// skip the search when there isn't enough free space left for this sprite
+		if len(pack.emptySpaces) > 0 && area(bounds) <= pack.free {
+			_, found = pack.find(bounds)
+		}
+
+		for !found {
+			before := pack.bounds.Size()
 			if err = pack.grow(bounds.Size(), i); err != nil {
 				return
 			}
+
+			// bail if growing didn't change anything, otherwise this would loop forever
+			if _, found = pack.find(bounds); !found && pack.bounds.Size() == before {
+				// give the sprite one more chance rotated before giving up on it
+				if !turned && pack.rotatable(data) {
+					turned = true
+					pack.turn(i)
+					data = pack.queued[i]
+					bounds = pack.footprint(data.pic)
+					_, found = pack.find(bounds)
+					continue
+				}
+				if bestEffort {
+					break
+				}
+				if pack.cfg.MaxSize != (image.Point{}) {
+					return size, fmt.Errorf("%w: id %d", ErrMaxSizeExceeded, data.id)
+				}
+				return size, fmt.Errorf("%w: id %d", ErrNoGrowth, data.id)
+			}
+		}
+
+		if !found {
+			if turned {
+				pack.turn(i)
+			}
+			pack.skip(i)
+			i--
+			continue
 		}
 
 		if err = pack.insert(data); err != nil {
 			return
 		}
+		if pack.cfg.OnStep != nil {
+			pack.cfg.OnStep(i, len(pack.emptySpaces))
+		}
+	}
+	pack.pad()
+
+	pack.planned = true
+	return pack.bounds.Size(), nil
+}
+
+// Commit allocates the packer texture and draws the placed textures into it, planning first if necessary.
+func (pack *Packer) Commit() (err error) {
+	if !pack.lock() {
+		return ErrAlreadyPacked
+	}
+	defer pack.unlock()
+	return pack.commit(nil)
+}
+
+// PackInto packs like Pack but draws into dst instead of allocating a new texture, dst must contain the planned bounds.
+// Only the sprites' pixels are written, the rest of dst is left as is. Image, SubImage and the like then read from
+// the part of dst within Bounds, sharing its pixels.
+func (pack *Packer) PackInto(dst *image.RGBA) (err error) {
+	if !pack.lock() {
+		return ErrAlreadyPacked
+	}
+	defer pack.unlock()
+
+	var size image.Point
+	if size, err = pack.plan(); err != nil {
+		return
+	}
+	if !pack.bounds.In(dst.Bounds()) {
+		return fmt.Errorf("%w: need %s, got %s", ErrBufferTooSmall, size, dst.Bounds().Size())
+	}
+	return pack.commit(dst)
+}
+
+// Helper to draw a sprite into the texture with its top left corner at the given point
+func (pack *Packer) blit(at image.Point, pic *image.RGBA) {
+	for x := 0; x < pic.Bounds().Dx(); x++ {
+		for y := 0; y < pic.Bounds().Dy(); y++ {
+			pack.pic.Set(x+at.X, y+at.Y, pic.At(x+pic.Rect.Min.X, y+pic.Rect.Min.Y))
+		}
+	}
+}
+
+// Helper to draw the placed textures into dst, or a newly allocated texture if dst is nil
+func (pack *Packer) commit(dst *image.RGBA) (err error) {
+	if pack.packed {
+		return ErrAlreadyPacked
+	}
+	if !pack.planned {
+		if _, err = pack.plan(); err != nil {
+			return
+		}
+	}
+	if err = pack.missing(); err != nil {
+		return
 	}
 
-	pack.pic = image.NewRGBA(pack.bounds)
-	for id, pic := range pack.images {
-		for x := 0; x < pic.Bounds().Dx(); x++ {
-			for y := 0; y < pic.Bounds().Dy(); y++ {
-				var (
-					rect = pack.rects[id]
-				)
-				pack.pic.Set(x+rect.Min.X, y+rect.Min.Y, pic.At(x, y))
+	keep := pack.cfg.Flags&FlagKeepSources != 0
+	stream := pack.cfg.Flags&FlagStreamBlit != 0 && !keep
+	if stream {
+		// the images map holds the only remaining references once the queue is dropped
+		pack.queued = nil
+	}
+
+	if dst != nil {
+		pack.pic = dst.SubImage(pack.bounds).(*image.RGBA)
+	} else {
+		pack.pic = image.NewRGBA(pack.bounds)
+	}
+	if workers := pack.cfg.Parallelism; workers > 1 {
+		// sprites never overlap, so each worker writes to its own part of the texture
+		var (
+			wg   sync.WaitGroup
+			jobs = make(chan queuedData)
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for data := range jobs {
+					pack.blit(pack.pixelsAt(data.id, data.pic), data.pic)
+				}
+			}()
+		}
+		for id, pic := range pack.images {
+			jobs <- queuedData{id: id, pic: pic}
+			if stream {
+				delete(pack.images, id)
+			}
+		}
+		close(jobs)
+		wg.Wait()
+	} else {
+		for id, pic := range pack.images {
+			pack.blit(pack.pixelsAt(id, pic), pic)
+			if stream {
+				delete(pack.images, id)
 			}
 		}
 	}
+	if p := pack.gutter(); p > 0 {
+		gutter := image.NewUniform(pack.cfg.GutterColor)
+		for _, r := range pack.rects {
+			draw.Draw(pack.pic, image.Rect(r.Max.X, r.Min.Y, r.Max.X+p, r.Max.Y+p), gutter, image.Point{}, draw.Src)
+			draw.Draw(pack.pic, image.Rect(r.Min.X, r.Max.Y, r.Max.X, r.Max.Y+p), gutter, image.Point{}, draw.Src)
+		}
+	}
 	pack.queued = nil
-	pack.emptySpaces = nil
-	pack.images = nil
+	if !keep {
+		pack.images = nil
+	}
 	pack.packed = true
 
 	return
 }
 
-// Saves the internal texture as a file on disk, the output type is defined by the filename extension
-func (pack *Packer) Save(filename string) (err error) {
+// Returns a copy of the empty spaces left in the texture
+func (pack *Packer) EmptySpaces() []image.Rectangle {
+	spaces := make([]image.Rectangle, len(pack.emptySpaces))
+	copy(spaces, pack.emptySpaces)
+	return spaces
+}
+
+// Reports whether a w by h sprite, plus its margin, would fit in one of the empty spaces left after packing without growing
+func (pack *Packer) Fits(w, h int) bool {
 	if !pack.packed {
+		pack.fail(ErrNotPacked)
+		return false
+	}
+	m := pack.margin()
+	_, found := pack.find(rect(0, 0, w+m, h+m))
+	return found
+}
+
+// Returns true once Pack (or Commit) has finished successfully
+func (pack *Packer) Packed() bool {
+	return pack.packed
+}
+
+// Helper to check that the packer has been packed and has a texture to read from
+func (pack *Packer) hasPic() bool {
+	return pack.packed && pack.pic != nil
+}
+
+// Helper to report an accessor failure, panics unless StrictErrors is set
+func (pack *Packer) fail(err error) {
+	if !pack.cfg.StrictErrors {
+		panic(err)
+	}
+	if pack.err == nil {
+		pack.err = err
+	}
+}
+
+// Returns the first error an accessor hit with StrictErrors set, or nil
+func (pack *Packer) Err() error {
+	return pack.err
+}
+
+// Returns the number of sprites waiting to be packed, zero once packed
+func (pack *Packer) QueuedCount() int {
+	return len(pack.queued)
+}
+
+// Returns the summed area of the sprites waiting to be packed, after trimming and without padding, zero once packed
+func (pack *Packer) QueuedArea() (total int) {
+	for _, data := range pack.queued {
+		if data.pic != nil {
+			total += area(data.pic.Rect)
+		}
+	}
+	return
+}
+
+// Returns the ids that were skipped by FlagBestEffort because they didn't fit, in the order they were skipped
+func (pack *Packer) Unplaced() []int {
+	return pack.unplaced
+}
+
+// Saves the internal texture as a file on disk, the output type is defined by the filename extension regardless of its case
+func (pack *Packer) Save(filename string) (err error) {
+	if !pack.hasPic() {
 		return ErrNotPacked
 	}
 
-	var (
-		file *os.File
-	)
+	ext := strings.ToLower(path.Ext(filename))
+	switch ext {
+	case ".jpeg", ".jpg":
+		if pack.cfg.Flags&FlagAllowAlphaLoss == 0 && !pack.pic.Opaque() {
+			return ErrAlphaLostInJPEG
+		}
+	}
 
-	if err = os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
-		return
+	img := pack.Output()
+	if pack.cfg.Flags&FlagPremultiplyOnSave != 0 {
+		switch pack.cfg.Format {
+		case FormatRGBA, FormatNRGBA:
+			img = premultiplied(pack.pic)
+		case FormatRGBA64:
+			img = premultiplied64(pack.pic)
+		}
 	}
 
-	if file, err = os.Create(filename); err != nil {
+	return writeAtomic(filename, func(file io.Writer) (err error) {
+		switch ext {
+		case ".png":
+			err = pack.encodePNG(file, img)
+		case ".jpeg", ".jpg":
+			var opts *jpeg.Options
+			if pack.cfg.Encode.JPEGQuality > 0 {
+				opts = &jpeg.Options{Quality: pack.cfg.Encode.JPEGQuality}
+			}
+			err = jpeg.Encode(file, img, opts)
+		default:
+			if enc, found := lookupFormat(ext); found {
+				err = enc(file, img)
+			} else {
+				err = ErrUnsupportedSaveExt
+			}
+		}
 		return
+	})
+}
+
+// Saves the internal texture by handing it to enc, which has full control over the encoding.
+// Like Save, the file is only replaced once enc succeeds.
+func (pack *Packer) SaveFunc(filename string, enc func(io.Writer, *image.RGBA) error) (err error) {
+	if !pack.hasPic() {
+		return ErrNotPacked
 	}
-	defer file.Close()
+	return writeAtomic(filename, func(w io.Writer) error {
+		return enc(w, pack.pic)
+	})
+}
 
-	switch path.Ext(filename) {
-	case ".png":
-		err = png.Encode(file, pack.pic)
-	case ".jpeg", ".jpg":
-		err = jpeg.Encode(file, pack.pic, nil)
-	default:
-		err = ErrUnsupportedSaveExt
+// Saves the planned texture as a png without allocating the whole texture, reading each row from the sources as it is encoded.
+// Call Plan first; this is meant for atlases too large to hold in memory, so it doesn't Commit the packer.
+func (pack *Packer) SaveStreamed(filename string) (err error) {
+	if !pack.planned || pack.packed {
+		return ErrNotPlanned
 	}
-	if err != nil {
+	if err = pack.missing(); err != nil {
 		return
 	}
 
-	return
+	return writeAtomic(filename, func(file io.Writer) error {
+		return pack.encodePNG(file, newLazyImage(pack))
+	})
+}
+
+// Saves the internal texture as a paletted png with at most maxColors colors.
+// Returns how many unique colors the texture has; when that is more than maxColors the colors were reduced using median cut.
+func (pack *Packer) SavePaletted(filename string, maxColors int) (unique int, err error) {
+	if !pack.hasPic() {
+		return 0, ErrNotPacked
+	}
+	if maxColors < 1 || maxColors > 256 {
+		return 0, ErrInvalidPaletteSize
+	}
+
+	colors := uniqueColors(pack.pic)
+	img := paletted(pack.pic, colors, maxColors)
+
+	return len(colors), writeAtomic(filename, func(file io.Writer) error {
+		return pack.encodePNG(file, img)
+	})
+}
+
+// Encodes img as a png with the configured compression, tagging it with the configured DPI
+func (pack *Packer) encodePNG(w io.Writer, img image.Image) error {
+	if pack.cfg.DPI > 0 {
+		w = newPhysWriter(w, pack.cfg.DPI)
+	}
+	enc := png.Encoder{CompressionLevel: pack.cfg.Encode.PNGCompression}
+	return enc.Encode(w, img)
+}
+
+// Saves the internal texture as a single frame gif, shown for delay hundredths of a second.
+// The texture is reduced to 256 colors with median cut if it has more.
+func (pack *Packer) SaveGIF(filename string, delay int) (err error) {
+	if !pack.hasPic() {
+		return ErrNotPacked
+	}
+
+	anim := &gif.GIF{
+		Image: []*image.Paletted{paletted(pack.pic, uniqueColors(pack.pic), 256)},
+		Delay: []int{delay},
+	}
+
+	return writeAtomic(filename, func(file io.Writer) error {
+		return gif.EncodeAll(file, anim)
+	})
 }
 
 // Sets the default Id for the packer
@@ -246,29 +1490,246 @@ func (pack *Packer) SetDefaultId(id int) {
 	pack.nfId = id
 }
 
+// Clears the default Id so that 'Get' panics again for ids that don't exist
+func (pack *Packer) ClearDefaultId() {
+	pack.nfId = -1
+}
+
 // Returns the subimage bounds from the given id
 func (pack *Packer) Get(id int) (rect image.Rectangle) {
 	if !pack.packed {
-		panic(ErrNotPacked)
+		pack.fail(ErrNotPacked)
+		return
 	}
 
 	var has bool
 	if rect, has = pack.rects[id]; !has {
 		if pack.nfId == -1 {
-			panic(ErrNotFoundNoDefault)
+			pack.fail(ErrNotFoundNoDefault)
+			return
 		}
 		rect = pack.rects[pack.nfId]
 	}
 	return
 }
 
-// Returns the subimage, as a copy, from the given id
-func (pack *Packer) SubImage(id int) (img *image.RGBA) {
+// Returns the subimage bounds from the given id and true, or the default sprite's bounds and false if the id doesn't exist.
+// If there is no default sprite either, an empty rectangle and false are returned.
+func (pack *Packer) GetOr(id int) (rect image.Rectangle, found bool) {
 	if !pack.packed {
-		panic(ErrNotPacked)
+		pack.fail(ErrNotPacked)
+		return
+	}
+
+	if rect, found = pack.rects[id]; !found && pack.nfId != -1 {
+		rect = pack.rects[pack.nfId]
+	}
+	return
+}
+
+// Returns the ids in the order they were inserted, regardless of the order they were packed in
+func (pack *Packer) InsertionOrder() []int {
+	order := make([]int, len(pack.order))
+	copy(order, pack.order)
+	return order
+}
+
+// Returns the ids of the placed sprites ordered by their place in the texture, top to bottom then left to right
+func (pack *Packer) ByPosition() []int {
+	ids := make([]int, 0, len(pack.rects))
+	for id := range pack.rects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := pack.rects[ids[i]].Min, pack.rects[ids[j]].Min
+		if a.Y != b.Y {
+			return a.Y < b.Y
+		}
+		return a.X < b.X
+	})
+	return ids
+}
+
+// Returns true if the sprite was rotated 90 degrees clockwise when it was inserted
+func (pack *Packer) IsRotated(id int) bool {
+	return pack.rotated[id]
+}
+
+// Describes how to map a point in the sprite's original image to the packed image.
+// Rotation is applied first: if rotated, (x, y) becomes (h-1-y, x) where h is the original image's height.
+// The offset, which accounts for both trimming and the sprite's place in the texture, is then added.
+func (pack *Packer) Transform(id int) (offset image.Point, rotated bool) {
+	if _, has := pack.rects[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+	return pack.Get(id).Min.Sub(pack.TrimRect(id).Min), pack.rotated[id]
+}
+
+// Sets the sprite's origin, the point in its original image that it is positioned by
+func (pack *Packer) SetOrigin(id int, origin image.Point) {
+	pack.origins[id] = origin
+}
+
+// Returns the origin set with SetOrigin, or the sprite's top left corner
+func (pack *Packer) Origin(id int) image.Point {
+	return pack.origins[id]
+}
+
+// Returns the sprite's bounds in the packed image and its origin as a fraction of its original size, from 0 to 1 on each axis
+func (pack *Packer) GetPivotRect(id int) (rect image.Rectangle, pivot [2]float64) {
+	if rect = pack.Get(id); rect.Empty() {
+		return
+	}
+	if _, has := pack.sizes[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+
+	var (
+		origin = pack.Origin(id)
+		size   = pack.SourceSize(id)
+	)
+	if size.X > 0 {
+		pivot[0] = float64(origin.X) / float64(size.X)
+	}
+	if size.Y > 0 {
+		pivot[1] = float64(origin.Y) / float64(size.Y)
+	}
+	return
+}
+
+// Returns the original size of the sprite with the given id as it was inserted, before any rotation
+func (pack *Packer) SourceSize(id int) (size image.Point) {
+	if _, has := pack.sizes[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+	if size = pack.sourceSize(id); pack.rotated[id] {
+		size = image.Pt(size.Y, size.X)
+	}
+	return
+}
+
+// Helper to get the untrimmed size of the sprite as it is stored, turned like the packed sprite if it was rotated
+func (pack *Packer) sourceSize(id int) (size image.Point) {
+	var has bool
+	if size, has = pack.sizes[id]; !has {
+		if pack.nfId == -1 {
+			pack.fail(ErrNotFoundNoDefault)
+			return
+		}
+		size = pack.sizes[pack.nfId]
+	}
+	return
+}
+
+// Returns the region of the original sprite that was kept by FlagTrim, relative to the sprite's top left corner.
+// Without trimming this covers the whole sprite.
+func (pack *Packer) TrimRect(id int) image.Rectangle {
+	if _, has := pack.sizes[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+	if r, has := pack.trims[id]; has {
+		return r
+	}
+	return image.Rectangle{Max: pack.sourceSize(id)}
+}
+
+// Returns the number of pixels FlagTrim removed from each sprite, by id
+func (pack *Packer) TrimSavings() map[int]int {
+	savings := make(map[int]int, len(pack.trims))
+	for id, r := range pack.trims {
+		savings[id] = area(image.Rectangle{Max: pack.sizes[id]}) - area(r)
+	}
+	return savings
+}
+
+// Returns a copy of the sprite with the given id at its original size, requires FlagKeepSources.
+// Borders removed by FlagTrim come back transparent, so they're only exact with a TrimAlphaThreshold of 0 and no TrimColor.
+func (pack *Packer) Source(id int) (img *image.RGBA) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+	if _, has := pack.sizes[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+	pic, has := pack.images[id]
+	if !has {
+		pack.fail(fmt.Errorf("%w: id %d", ErrSourceNotKept, id))
+		return
+	}
+
+	img = image.NewRGBA(image.Rectangle{Max: pack.sourceSize(id)})
+	draw.Draw(img, pack.TrimRect(id), pic, pic.Rect.Min, draw.Src)
+	return
+}
+
+// Returns a copy of the subimage from the given id turned back the way it was inserted if it was rotated when packed
+func (pack *Packer) SubImageUpright(id int) *image.RGBA {
+	img := pack.SubImageCopy(id)
+	if img == nil {
+		return nil
+	}
+	if _, has := pack.rects[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+	if pack.rotated[id] {
+		return unrotate(img)
+	}
+	return img
+}
+
+// Multiplies every pixel of the sprite in the packed image by the mask color, SubImage reflects the change
+func (pack *Packer) Tint(id int, mask color.Color) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+
+	var (
+		r              = pack.Get(id)
+		mr, mg, mb, ma = mask.RGBA()
+	)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := pack.pic.RGBAAt(x, y)
+			pack.pic.SetRGBA(x, y, color.RGBA{
+				R: uint8(uint32(c.R) * mr / 0xffff),
+				G: uint8(uint32(c.G) * mg / 0xffff),
+				B: uint8(uint32(c.B) * mb / 0xffff),
+				A: uint8(uint32(c.A) * ma / 0xffff),
+			})
+		}
+	}
+}
+
+// Returns an independent copy of the subimage from the given id that is safe to modify
+func (pack *Packer) SubImageCopy(id int) (img *image.RGBA) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+
+	r := pack.Get(id)
+	img = image.NewRGBA(image.Rectangle{Max: r.Size()})
+	draw.Draw(img, img.Rect, pack.pic, r.Min, draw.Src)
+	return
+}
+
+// Returns the subimage from the given id as a view into the packed image, so changes to it show up in the atlas.
+// Use SubImageCopy for an image that can be modified freely.
+func (pack *Packer) SubImage(id int) (img *image.RGBA) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
 	}
 
 	r := pack.Get(id)
+	if _, has := pack.rects[id]; !has && pack.nfId != -1 {
+		id = pack.nfId
+	}
+	if _, has := pack.rects[id]; !has {
+		return nil
+	}
 	i := pack.pic.PixOffset(r.Min.X, r.Min.Y)
 	return &image.RGBA{
 		Pix:    pack.pic.Pix[i:],
@@ -277,10 +1738,227 @@ func (pack *Packer) SubImage(id int) (img *image.RGBA) {
 	}
 }
 
+// Returns a SHA-256 checksum of the packed image and sprite locations, useful to detect if a regenerated atlas changed
+func (pack *Packer) Checksum() (sum [32]byte) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+
+	ids := make([]int, 0, len(pack.rects))
+	for id := range pack.rects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	h := sha256.New()
+	h.Write(pack.pic.Pix)
+	for _, id := range ids {
+		r := pack.rects[id]
+		binary.Write(h, binary.LittleEndian, []int64{int64(id), int64(r.Min.X), int64(r.Min.Y), int64(r.Max.X), int64(r.Max.Y)})
+	}
+	copy(sum[:], h.Sum(nil))
+	return
+}
+
+// Reports whether both packers have the same bounds, sprite locations and pixels
+func (pack *Packer) Equal(other *Packer) bool {
+	return pack.Diff(other) == ""
+}
+
+// Describes the first difference between the two packers, or returns an empty string if they are equal
+func (pack *Packer) Diff(other *Packer) string {
+	if !pack.hasPic() || !other.hasPic() {
+		return "Both packers must be packed"
+	}
+	if pack.bounds != other.bounds {
+		return fmt.Sprintf("Bounds differ: %s, %s", pack.bounds, other.bounds)
+	}
+	if len(pack.rects) != len(other.rects) {
+		return fmt.Sprintf("Sprite counts differ: %d, %d", len(pack.rects), len(other.rects))
+	}
+
+	ids := make([]int, 0, len(pack.rects))
+	for id := range pack.rects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		r, has := other.rects[id]
+		if !has {
+			return fmt.Sprintf("Id %d is missing", id)
+		}
+		if r != pack.rects[id] {
+			return fmt.Sprintf("Id %d rects differ: %s, %s", id, pack.rects[id], r)
+		}
+	}
+
+	for y := pack.bounds.Min.Y; y < pack.bounds.Max.Y; y++ {
+		for x := pack.bounds.Min.X; x < pack.bounds.Max.X; x++ {
+			if c1, c2 := pack.pic.RGBAAt(x, y), other.pic.RGBAAt(x, y); c1 != c2 {
+				return fmt.Sprintf("Pixels at (%d, %d) differ: %v, %v", x, y, c1, c2)
+			}
+		}
+	}
+
+	return ""
+}
+
+// Draws the entire packed image over dst with its top left corner at the given point
+func (pack *Packer) Blit(dst *image.RGBA, at image.Point) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+	r := pack.pic.Bounds()
+	draw.Draw(dst, r.Sub(r.Min).Add(at), pack.pic, r.Min, draw.Over)
+}
+
+// Draws the entire packed image over dst, scaled to fill dstRect
+func (pack *Packer) BlitScaled(dst *image.RGBA, dstRect image.Rectangle) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+	xdraw.CatmullRom.Scale(dst, dstRect, pack.pic, pack.pic.Bounds(), draw.Over, nil)
+}
+
+// Returns progressively half sized copies of the packed image, largest first, made with a box filter.
+// At most levels copies are made, stopping at 1x1; zero or less makes every level down to 1x1.
+func (pack *Packer) Mipmaps(levels int) (mips []*image.RGBA) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return
+	}
+
+	for prev := pack.pic; levels <= 0 || len(mips) < levels; {
+		if prev.Bounds().Dx() <= 1 && prev.Bounds().Dy() <= 1 {
+			break
+		}
+		prev = halve(prev)
+		mips = append(mips, prev)
+	}
+	return
+}
+
+// Returns the smallest rectangle holding every placed sprite, leaving out any empty margin the texture grew past them
+func (pack *Packer) ContentBounds() (bounds image.Rectangle) {
+	if !pack.packed {
+		pack.fail(ErrNotPacked)
+		return
+	}
+	for _, r := range pack.rects {
+		bounds = bounds.Union(r)
+	}
+	return
+}
+
+// Returns the number of bytes the packed image's pixels take up
+func (pack *Packer) MemoryBytes() int {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return 0
+	}
+	return len(pack.pic.Pix)
+}
+
+// Returns the bounds of the packed image
+func (pack *Packer) Bounds() image.Rectangle {
+	if !pack.packed {
+		pack.fail(ErrNotPacked)
+		return image.Rectangle{}
+	}
+	return pack.bounds
+}
+
+// Returns the size of the packed image
+func (pack *Packer) Size() image.Point {
+	return pack.Bounds().Size()
+}
+
+// Returns an image of the atlas that reads each pixel from the source sprites on demand, so sampling a few pixels
+// doesn't need the full texture. Usable after Plan, or after Pack with FlagKeepSources. Not safe for concurrent use.
+func (pack *Packer) LazyImage() image.Image {
+	if !pack.planned {
+		pack.fail(ErrNotPlanned)
+		return nil
+	}
+	if pack.packed && pack.cfg.Flags&FlagKeepSources == 0 {
+		pack.fail(ErrSourceNotKept)
+		return nil
+	}
+	if err := pack.missing(); err != nil {
+		pack.fail(err)
+		return nil
+	}
+	return newLazyImage(pack)
+}
+
+// Returns a function that calls yield with the starting y and the pixels of each band of rows of the packed image,
+// top to bottom, stopping early if yield returns false. The pixels aren't copied and rows are Image().Stride bytes apart.
+// Its type matches iter.Seq2[int, []byte], so it can be ranged over from modules using Go 1.23 or later.
+func (pack *Packer) Scanlines(band int) func(yield func(int, []byte) bool) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return func(yield func(int, []byte) bool) {}
+	}
+	if band < 1 {
+		band = 1
+	}
+
+	return func(yield func(int, []byte) bool) {
+		r := pack.pic.Rect.Intersect(pack.bounds)
+		for y := r.Min.Y; y < r.Max.Y; y += band {
+			last := y + band - 1
+			if last >= r.Max.Y {
+				last = r.Max.Y - 1
+			}
+			start, end := pack.pic.PixOffset(r.Min.X, y), pack.pic.PixOffset(r.Max.X, last)
+			if !yield(y, pack.pic.Pix[start:end]) {
+				return
+			}
+		}
+	}
+}
+
+// Returns the entire packed image converted to the configured Format, the concrete type matches the format.
+// With FormatRGBA this is the same image as Image, otherwise it's a copy.
+func (pack *Packer) Output() (img image.Image) {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return nil
+	}
+
+	var dst draw.Image
+	switch r := pack.pic.Bounds(); pack.cfg.Format {
+	case FormatNRGBA:
+		dst = image.NewNRGBA(r)
+	case FormatGray:
+		dst = image.NewGray(r)
+	case FormatRGBA64:
+		dst = image.NewRGBA64(r)
+	default:
+		return pack.pic
+	}
+	draw.Draw(dst, dst.Bounds(), pack.pic, pack.pic.Bounds().Min, draw.Src)
+	return dst
+}
+
+// Returns the packed image for drawing onto with image/draw.
+// It is the packer's own texture, not a copy, so drawing onto it changes what Image, SubImage and Save see.
+func (pack *Packer) DrawImage() draw.Image {
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return nil
+	}
+	return pack.pic
+}
+
 // Returns the entire packed image
 func (pack *Packer) Image() *image.RGBA {
-	if !pack.packed {
-		panic(ErrNotPacked)
+	if !pack.hasPic() {
+		pack.fail(ErrNotPacked)
+		return nil
 	}
 	return pack.pic
 }
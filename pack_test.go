@@ -1,16 +1,28 @@
 package rectpack_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
+	"io/fs"
+	"math"
 	"math/rand"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/dusk125/rectpack"
 	"golang.org/x/image/colornames"
@@ -135,3 +147,2893 @@ func Save(filename string, img image.Image) (err error) {
 
 	return
 }
+
+func noOverlap(pack *rectpack.Packer, n int) (err error) {
+	bounds := pack.Image().Bounds()
+	for i := 0; i < n; i++ {
+		ri := pack.Get(i)
+		if !ri.In(bounds) {
+			return fmt.Errorf("%d is outside of the atlas: %s not in %s", i, ri, bounds)
+		}
+		for j := i + 1; j < n; j++ {
+			if rj := pack.Get(j); ri.Overlaps(rj) {
+				return fmt.Errorf("%d and %d overlap: %s, %s", i, j, ri, rj)
+			}
+		}
+	}
+	return nil
+}
+
+func TestGuillotineAlgorithm(t *testing.T) {
+	sizes := []image.Point{{8, 32}, {32, 16}, {8, 16}, {8, 8}, {24, 24}, {32, 32}}
+	areas := make(map[rectpack.CreateFlags]int)
+	for _, flags := range []rectpack.CreateFlags{0, rectpack.GuillotineAlgorithm} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: flags})
+		for i, s := range sizes {
+			pack.Insert(i, fill(s.X, s.Y, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if err := noOverlap(pack, len(sizes)); err != nil {
+			t.Error(err)
+		}
+		b := pack.Image().Bounds()
+		areas[flags] = b.Dx() * b.Dy()
+	}
+	if areas[rectpack.GuillotineAlgorithm] >= areas[0] {
+		t.Errorf("Guillotine area %d is not smaller than split area %d", areas[rectpack.GuillotineAlgorithm], areas[0])
+	}
+}
+
+func TestPremultiplyOnSave(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagPremultiplyOnSave})
+	pack.Insert(0, fill(4, 4, color.NRGBA{R: 255, A: 128}))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	filename := path.Join(t.TempDir(), "premultiplied.png")
+	if err := pack.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := color.NRGBA{R: 128, A: 128}
+	if got := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); got != expected {
+		t.Errorf("Expected: %v, Got: %v", expected, got)
+	}
+	if got := pack.Image().RGBAAt(0, 0); got != (color.RGBA{R: 128, A: 128}) {
+		t.Errorf("In-memory atlas changed: %v", got)
+	}
+
+	// the configured format is kept
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagPremultiplyOnSave, Format: rectpack.FormatRGBA64})
+	pack.Insert(0, fill(4, 4, color.NRGBA{R: 255, A: 128}))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img, err = png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	expected64 := color.NRGBA64{R: 128 * 0x101, A: 128 * 0x101}
+	if got, ok := img.(*image.NRGBA64); !ok || got.NRGBA64At(0, 0) != expected64 {
+		t.Errorf("Expected 16 bit %v, Got: %T %v", expected64, img, img.At(0, 0))
+	}
+}
+
+func TestSpaceSort(t *testing.T) {
+	sizes := []image.Point{{8, 32}, {32, 16}, {8, 16}, {8, 8}, {24, 24}, {32, 32}, {16, 8}, {4, 40}}
+	for name, sorter := range map[string]rectpack.SpaceSort{
+		"SmallestFirst": rectpack.SpaceSmallestFirst,
+		"LargestFirst":  rectpack.SpaceLargestFirst,
+		"AreaAspect":    rectpack.SpaceAreaAspect,
+	} {
+		t.Run(name, func(t *testing.T) {
+			pack := rectpack.NewPacker(rectpack.PackerCfg{SpaceSort: sorter})
+			for i, s := range sizes {
+				pack.Insert(i, fill(s.X, s.Y, colornames.Red))
+			}
+			if err := pack.Pack(); err != nil {
+				t.Fatal(err)
+			}
+			if err := noOverlap(pack, len(sizes)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func BenchmarkPackPerfect(b *testing.B) {
+	tile := fill(16, 16, colornames.Red)
+	for n := 0; n < b.N; n++ {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		for i := 0; i < 256; i++ {
+			pack.Insert(i, tile)
+		}
+		if err := pack.Pack(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestInsertFlipped(t *testing.T) {
+	pic := fill(8, 4, colornames.Red)
+	for x := 4; x < 8; x++ {
+		for y := 0; y < 4; y++ {
+			pic.Set(x, y, colornames.Blue)
+		}
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertFlipped(0, pic, true, false)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	img := pack.SubImage(0)
+	for x := 0; x < 8; x++ {
+		expected := colornames.Blue
+		if x >= 4 {
+			expected = colornames.Red
+		}
+		if got := img.RGBAAt(x, 0); got != expected {
+			t.Errorf("At: %d, Expected: %v, Got: %v", x, expected, got)
+		}
+	}
+}
+
+func TestSavePaletted(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	for i, c := range []color.Color{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow, colornames.Purple, colornames.Orange} {
+		pack.Insert(i, fill(8, 8, c))
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	filename := path.Join(t.TempDir(), "paletted.png")
+	unique, err := pack.SavePaletted(filename, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unique != 7 {
+		t.Errorf("Expected 7 unique colors to be reported, Got: %d", unique)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paletted, ok := img.(*image.Paletted)
+	if !ok {
+		t.Fatalf("Expected a paletted image, Got: %T", img)
+	}
+	if len(paletted.Palette) > 4 {
+		t.Errorf("Palette has %d colors, Expected at most 4", len(paletted.Palette))
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	t.Run("SpriteTooLarge", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{MaxSize: image.Pt(64, 64)})
+		pack.Insert(0, fill(16, 16, colornames.Red))
+		pack.Insert(3, fill(128, 16, colornames.Red))
+		err := pack.Pack()
+		if !errors.Is(err, rectpack.ErrSpriteTooLarge) {
+			t.Fatalf("Expected: %v, Got: %v", rectpack.ErrSpriteTooLarge, err)
+		}
+		if !strings.Contains(err.Error(), "id 3") {
+			t.Errorf("Error doesn't name id 3: %v", err)
+		}
+	})
+	t.Run("Exceeded", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{MaxSize: image.Pt(64, 64)})
+		for i := 0; i < 5; i++ {
+			pack.Insert(i, fill(32, 32, colornames.Red))
+		}
+		if err := pack.Pack(); !errors.Is(err, rectpack.ErrMaxSizeExceeded) {
+			t.Fatalf("Expected: %v, Got: %v", rectpack.ErrMaxSizeExceeded, err)
+		}
+	})
+	t.Run("Fits", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{MaxSize: image.Pt(64, 64)})
+		for i := 0; i < 4; i++ {
+			pack.Insert(i, fill(32, 32, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if size := pack.Image().Bounds().Size(); size.X > 64 || size.Y > 64 {
+			t.Errorf("Atlas %s is larger than the maximum size", size)
+		}
+	})
+}
+
+func TestPlan(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(16, 16, colornames.Red))
+	pack.Insert(1, fill(8, 32, colornames.Blue))
+
+	size, err := pack.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != rectpack.ErrNotPacked {
+				t.Errorf("Expected Image to panic with %v, Got: %v", rectpack.ErrNotPacked, r)
+			}
+		}()
+		pack.Image()
+	}()
+
+	if err = pack.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got := pack.Image().Bounds().Size(); got != size {
+		t.Errorf("Expected: %s, Got: %s", size, got)
+	}
+	if err := colorEq(pack.SubImage(1), 8, 32, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeOptions(t *testing.T) {
+	sizes := make(map[int]int64)
+	for _, quality := range []int{1, 100} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Encode: rectpack.EncodeOptions{JPEGQuality: quality}})
+		for i, c := range []color.Color{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow} {
+			pack.Insert(i, fill(32, 32, c))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		filename := path.Join(t.TempDir(), "quality.jpg")
+		if err := pack.Save(filename); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[quality] = info.Size()
+	}
+	if sizes[1] >= sizes[100] {
+		t.Errorf("Low quality file (%d bytes) isn't smaller than high quality file (%d bytes)", sizes[1], sizes[100])
+	}
+}
+
+func TestSortByMaxSide(t *testing.T) {
+	sizes := []image.Point{{12, 12}, {4, 32}, {36, 4}}
+	areas := make(map[string]int)
+	for name, sorter := range map[string]rectpack.SortFunc{
+		"Area":    rectpack.SortByArea,
+		"MaxSide": rectpack.SortByMaxSide,
+	} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{SortFunc: sorter})
+		for i, s := range sizes {
+			pack.Insert(i, fill(s.X, s.Y, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if err := noOverlap(pack, len(sizes)); err != nil {
+			t.Error(err)
+		}
+		b := pack.Image().Bounds()
+		areas[name] = b.Dx() * b.Dy()
+	}
+	if areas["MaxSide"] >= areas["Area"] {
+		t.Errorf("Max side area %d is not smaller than area sort area %d", areas["MaxSide"], areas["Area"])
+	}
+}
+
+func TestPackWithResult(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(32, 32, colornames.Red))
+	pack.Insert(1, fill(16, 16, colornames.Blue))
+	pack.Insert(2, fill(64, 8, colornames.Green))
+
+	result, err := pack.PackWithResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := pack.Image().Bounds()
+	if result.Size != bounds.Size() {
+		t.Errorf("Size, Expected: %s, Got: %s", bounds.Size(), result.Size)
+	}
+	if result.Grows < 1 {
+		t.Errorf("Expected at least one grow, Got: %d", result.Grows)
+	}
+	used := 32*32 + 16*16 + 64*8
+	if expected := float64(used) / float64(bounds.Dx()*bounds.Dy()); result.Efficiency != expected {
+		t.Errorf("Efficiency, Expected: %f, Got: %f", expected, result.Efficiency)
+	}
+}
+
+func BenchmarkPackGrowth(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	sprites := make([]*image.RGBA, 200)
+	for i := range sprites {
+		sprites[i] = image.NewRGBA(image.Rect(0, 0, 1+r.Intn(32), 1+r.Intn(32)))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		for i, pic := range sprites {
+			pack.Insert(i, pic)
+		}
+		if _, err := pack.Plan(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestStreamBlit(t *testing.T) {
+	images := make(map[rectpack.CreateFlags]*image.RGBA)
+	for _, flags := range []rectpack.CreateFlags{0, rectpack.FlagStreamBlit} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: flags})
+		for i, c := range []color.Color{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow} {
+			pack.Insert(i, fill(8*(i+1), 16, c))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		images[flags] = pack.Image()
+	}
+
+	normal, streamed := images[0], images[rectpack.FlagStreamBlit]
+	if normal.Bounds() != streamed.Bounds() || !bytes.Equal(normal.Pix, streamed.Pix) {
+		t.Error("Streamed texture doesn't match the normal texture")
+	}
+}
+
+func BenchmarkStreamBlit(b *testing.B) {
+	for name, flags := range map[string]rectpack.CreateFlags{"Normal": 0, "Stream": rectpack.FlagStreamBlit} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: flags})
+				for i := 0; i < 16; i++ {
+					pack.Insert(i, image.NewRGBA(image.Rect(0, 0, 128, 128)))
+				}
+				if err := pack.Pack(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBestEffort(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{
+		Flags:   rectpack.FlagBestEffort,
+		MaxSize: image.Pt(64, 64),
+	})
+	pack.Insert(0, fill(64, 40, colornames.Red))
+	pack.Insert(1, fill(64, 30, colornames.Green))
+	pack.Insert(2, fill(64, 20, colornames.Blue))
+	pack.Insert(3, fill(100, 1, colornames.Yellow))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if unplaced := pack.Unplaced(); fmt.Sprint(unplaced) != fmt.Sprint([]int{3, 1}) {
+		t.Errorf("Unplaced, Expected: [3 1], Got: %v", unplaced)
+	}
+	if err := colorEq(pack.SubImage(0), 64, 40, colornames.Red); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(2), 64, 20, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	checksum := func(c color.Color) [32]byte {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		pack.Insert(0, fill(16, 16, colornames.Red))
+		pack.Insert(1, fill(8, 24, c))
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		return pack.Checksum()
+	}
+
+	if checksum(colornames.Blue) != checksum(colornames.Blue) {
+		t.Error("Identical packs have different checksums")
+	}
+	if checksum(colornames.Blue) == checksum(colornames.Green) {
+		t.Error("Different packs have the same checksum")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	newPack := func(c color.Color) *rectpack.Packer {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		pack.Insert(0, fill(16, 16, colornames.Red))
+		pack.Insert(1, fill(8, 24, c))
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		return pack
+	}
+
+	golden := newPack(colornames.Blue)
+	if !golden.Equal(golden) {
+		t.Errorf("Packer isn't equal to itself: %s", golden.Diff(golden))
+	}
+	if !golden.Equal(newPack(colornames.Blue)) {
+		t.Errorf("Identical packers aren't equal: %s", golden.Diff(newPack(colornames.Blue)))
+	}
+	if altered := newPack(colornames.Green); golden.Equal(altered) {
+		t.Error("Packers with different colors are equal")
+	} else if !strings.HasPrefix(golden.Diff(altered), "Pixels") {
+		t.Errorf("Expected a pixel difference, Got: %s", golden.Diff(altered))
+	}
+}
+
+func TestInsertRaw(t *testing.T) {
+	const w, h, stride = 4, 3, 20
+	pix := make([]byte, stride*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			copy(pix[y*stride+x*4:], []byte{0, 0, 255, 255})
+		}
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	if err := pack.InsertRaw(0, pix, w, h, stride); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.InsertRaw(1, pix, w, h, 8); !errors.Is(err, rectpack.ErrInvalidStride) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrInvalidStride, err)
+	}
+	if err := pack.InsertRaw(2, pix[:stride], w, h, stride); !errors.Is(err, rectpack.ErrShortPixBuffer) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrShortPixBuffer, err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := colorEq(pack.SubImage(0), w, h, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNoSort(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagNoSort})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	pack.Insert(1, fill(32, 32, colornames.Blue))
+	pack.Insert(2, fill(16, 16, colornames.Green))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := pack.Get(0); r.Min != image.Pt(0, 0) {
+		t.Errorf("First inserted sprite wasn't placed first: %s", r)
+	}
+	if err := noOverlap(pack, 3); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFixedWidth(t *testing.T) {
+	t.Run("Column", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{
+			Flags:       rectpack.FlagFixedWidth,
+			InitialSize: image.Pt(256, 0),
+		})
+		for i := 0; i < 10; i++ {
+			pack.Insert(i, fill(32+i*20, 40, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if dx := pack.Image().Bounds().Dx(); dx != 256 {
+			t.Errorf("Width, Expected: 256, Got: %d", dx)
+		}
+		if err := noOverlap(pack, 10); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("TooWide", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{
+			Flags:       rectpack.FlagFixedWidth,
+			InitialSize: image.Pt(256, 0),
+		})
+		pack.Insert(4, fill(300, 10, colornames.Red))
+		if err := pack.Pack(); !errors.Is(err, rectpack.ErrSpriteTooWide) || !strings.Contains(err.Error(), "id 4") {
+			t.Errorf("Expected: %v naming id 4, Got: %v", rectpack.ErrSpriteTooWide, err)
+		}
+	})
+}
+
+func TestFixedHeight(t *testing.T) {
+	t.Run("Strip", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{
+			Flags:       rectpack.FlagFixedHeight,
+			InitialSize: image.Pt(0, 128),
+		})
+		for i := 0; i < 10; i++ {
+			pack.Insert(i, fill(24, 16+i*10, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if dy := pack.Image().Bounds().Dy(); dy != 128 {
+			t.Errorf("Height, Expected: 128, Got: %d", dy)
+		}
+		if err := noOverlap(pack, 10); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("TooTall", func(t *testing.T) {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{
+			Flags:       rectpack.FlagFixedHeight,
+			InitialSize: image.Pt(0, 128),
+		})
+		pack.Insert(2, fill(10, 200, colornames.Red))
+		if err := pack.Pack(); !errors.Is(err, rectpack.ErrSpriteTooTall) || !strings.Contains(err.Error(), "id 2") {
+			t.Errorf("Expected: %v naming id 2, Got: %v", rectpack.ErrSpriteTooTall, err)
+		}
+	})
+}
+
+func TestTargetAspect(t *testing.T) {
+	const target = 16.0 / 9.0
+	distance := func(aspect float64) float64 {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{TargetAspect: aspect})
+		for i := 0; i < 12; i++ {
+			pack.Insert(i, fill(16, 16, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if err := noOverlap(pack, 12); err != nil {
+			t.Error(err)
+		}
+		b := pack.Image().Bounds()
+		return math.Abs(math.Log(float64(b.Dx()) / float64(b.Dy()) / target))
+	}
+
+	if def, targeted := distance(0), distance(target); targeted >= def {
+		t.Errorf("Targeted growth (%f) isn't closer to the target ratio than the default growth (%f)", targeted, def)
+	}
+
+	// growing one axis at a time must still fit every sprite placed before the growth back in
+	rng := rand.New(rand.NewSource(5))
+	for _, aspect := range []float64{0.5, 1, 4} {
+		for set := 0; set < 200; set++ {
+			pack := rectpack.NewPacker(rectpack.PackerCfg{TargetAspect: aspect})
+			n := rng.Intn(25) + 1
+			for i := 0; i < n; i++ {
+				pack.Insert(i, fill(rng.Intn(30)+1, rng.Intn(30)+1, colornames.Red))
+			}
+			if err := pack.Pack(); err != nil {
+				t.Fatalf("aspect %v, set %d: %v", aspect, set, err)
+			}
+			if err := noOverlap(pack, n); err != nil {
+				t.Fatalf("aspect %v, set %d: %v", aspect, set, err)
+			}
+		}
+	}
+
+	// growth falls back to the other axis once the preferred one can't grow any further
+	for name, cfg := range map[string]rectpack.PackerCfg{
+		"fixed width": {Flags: rectpack.FlagFixedWidth, InitialSize: image.Pt(64, 0), TargetAspect: 4},
+		"max size":    {MaxSize: image.Pt(64, 1024), TargetAspect: 4},
+	} {
+		pack := rectpack.NewPacker(cfg)
+		for i := 0; i < 10; i++ {
+			pack.Insert(i, fill(32, 32, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if w := pack.Size().X; w != 64 {
+			t.Errorf("%s: Expected a width of 64, Got: %d", name, w)
+		}
+		if err := noOverlap(pack, 10); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestSourceSize(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(12, 7, colornames.Red))
+	pack.Insert(1, fill(3, 20, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, expected := range map[int]image.Point{0: {12, 7}, 1: {3, 20}} {
+		if got := pack.SourceSize(id); got != expected {
+			t.Errorf("%d, Expected: %s, Got: %s", id, expected, got)
+		}
+	}
+}
+
+func TestBlit(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Offset", func(t *testing.T) {
+		canvas := fill(64, 64, colornames.White)
+		pack.Blit(canvas, image.Pt(10, 20))
+		for _, p := range []image.Point{{10, 20}, {17, 27}} {
+			if got := canvas.RGBAAt(p.X, p.Y); got != colornames.Red {
+				t.Errorf("At: %s, Expected: %v, Got: %v", p, colornames.Red, got)
+			}
+		}
+		for _, p := range []image.Point{{9, 20}, {18, 27}, {10, 28}} {
+			if got := canvas.RGBAAt(p.X, p.Y); got != colornames.White {
+				t.Errorf("At: %s, Expected: %v, Got: %v", p, colornames.White, got)
+			}
+		}
+	})
+	t.Run("Scaled", func(t *testing.T) {
+		canvas := fill(64, 64, colornames.White)
+		pack.BlitScaled(canvas, image.Rect(16, 16, 48, 48))
+		for _, p := range []image.Point{{16, 16}, {32, 32}, {47, 47}} {
+			if got := canvas.RGBAAt(p.X, p.Y); got != colornames.Red {
+				t.Errorf("At: %s, Expected: %v, Got: %v", p, colornames.Red, got)
+			}
+		}
+		if got := canvas.RGBAAt(15, 32); got != colornames.White {
+			t.Errorf("At: (15, 32), Expected: %v, Got: %v", colornames.White, got)
+		}
+	})
+}
+
+func TestInsertionOrder(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(5, fill(4, 4, colornames.Red))
+	pack.Insert(2, fill(16, 16, colornames.Green))
+	pack.Insert(9, fill(8, 8, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if order := pack.InsertionOrder(); fmt.Sprint(order) != fmt.Sprint([]int{5, 2, 9}) {
+		t.Errorf("Expected: [5 2 9], Got: %v", order)
+	}
+}
+
+func TestFailedPack(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{MaxSize: image.Pt(16, 16)})
+	pack.Insert(0, fill(16, 16, colornames.Red))
+	pack.Insert(1, fill(16, 16, colornames.Blue))
+	if err := pack.Pack(); err == nil {
+		t.Fatal("Expected the pack to fail")
+	}
+
+	if err := pack.Save(path.Join(t.TempDir(), "failed.png")); !errors.Is(err, rectpack.ErrNotPacked) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotPacked, err)
+	}
+	for name, f := range map[string]func(){
+		"Image":    func() { pack.Image() },
+		"SubImage": func() { pack.SubImage(0) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != rectpack.ErrNotPacked {
+					t.Errorf("%s, Expected panic: %v, Got: %v", name, rectpack.ErrNotPacked, r)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestGetOr(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(16, 16, colornames.Red))
+	pack.Insert(1, fill(8, 8, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, found := pack.GetOr(1); !found || r != pack.Get(1) {
+		t.Errorf("Present id, Expected: %s true, Got: %s %v", pack.Get(1), r, found)
+	}
+	if r, found := pack.GetOr(7); found || r != (image.Rectangle{}) {
+		t.Errorf("Missing id without default, Expected: empty false, Got: %s %v", r, found)
+	}
+	pack.SetDefaultId(0)
+	if r, found := pack.GetOr(7); found || r != pack.Get(0) {
+		t.Errorf("Missing id with default, Expected: %s false, Got: %s %v", pack.Get(0), r, found)
+	}
+}
+
+func TestClearDefaultId(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(16, 16, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	pack.SetDefaultId(0)
+	if r := pack.Get(3); r != pack.Get(0) {
+		t.Errorf("Expected the default sprite %s, Got: %s", pack.Get(0), r)
+	}
+
+	pack.ClearDefaultId()
+	defer func() {
+		if r := recover(); r != rectpack.ErrNotFoundNoDefault {
+			t.Errorf("Expected panic: %v, Got: %v", rectpack.ErrNotFoundNoDefault, r)
+		}
+	}()
+	pack.Get(3)
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInsertFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sprites/red.png": &fstest.MapFile{Data: encodePNG(t, fill(6, 9, colornames.Red))},
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	if err := pack.InsertFromFS(0, fsys, "sprites/red.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.InsertFromFS(1, fsys, "sprites/missing.png"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected: %v, Got: %v", fs.ErrNotExist, err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := colorEq(pack.SubImage(0), 6, 9, colornames.Red); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInsertAllFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/sprites/0.png":    &fstest.MapFile{Data: encodePNG(t, fill(4, 4, colornames.Red))},
+		"assets/sprites/1.png":    &fstest.MapFile{Data: encodePNG(t, fill(8, 4, colornames.Green))},
+		"assets/sprites/2.png":    &fstest.MapFile{Data: encodePNG(t, fill(4, 8, colornames.Blue))},
+		"assets/sprites/notes":    &fstest.MapFile{Data: []byte("not an image")},
+		"assets/other/ignore.png": &fstest.MapFile{Data: encodePNG(t, fill(1, 1, colornames.Black))},
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	err := pack.InsertAllFromFS(fsys, "assets/sprites", func(name string) int {
+		id, _ := strconv.Atoi(strings.TrimSuffix(path.Base(name), ".png"))
+		return id
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if order := pack.InsertionOrder(); len(order) != 3 {
+		t.Errorf("Expected 3 sprites, Got: %v", order)
+	}
+	if err := colorEq(pack.SubImage(0), 4, 4, colornames.Red); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(1), 8, 4, colornames.Green); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(2), 4, 8, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInsertAllFromFSCorrupt(t *testing.T) {
+	corrupt := encodePNG(t, fill(4, 4, colornames.Red))
+	fsys := fstest.MapFS{
+		"sprites/0.png": &fstest.MapFile{Data: encodePNG(t, fill(4, 4, colornames.Red))},
+		"sprites/1.png": &fstest.MapFile{Data: corrupt[:len(corrupt)/2]},
+		"sprites/2.png": &fstest.MapFile{Data: encodePNG(t, fill(4, 8, colornames.Blue))},
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	err := pack.InsertAllFromFS(fsys, "sprites", func(name string) int {
+		id, _ := strconv.Atoi(strings.TrimSuffix(path.Base(name), ".png"))
+		return id
+	})
+
+	var multi rectpack.MultiError
+	if !errors.As(err, &multi) || len(multi) != 1 || multi[0].Name != "sprites/1.png" {
+		t.Fatalf("Expected a failure for sprites/1.png, Got: %v", err)
+	}
+	var format png.FormatError
+	if !errors.As(err, &format) {
+		t.Errorf("Expected the aggregate to unwrap to a png.FormatError, Got: %v", err)
+	}
+	if order := pack.InsertionOrder(); len(order) != 2 || order[0] != 0 || order[1] != 2 {
+		t.Errorf("Expected sprites 0 and 2 to be queued, Got: %v", order)
+	}
+}
+
+func TestPacked(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if pack.Packed() {
+		t.Error("Packed before Pack was called")
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if !pack.Packed() {
+		t.Error("Not packed after Pack was called")
+	}
+}
+
+func TestGrowFactor(t *testing.T) {
+	grows := func(factor float64) int {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{GrowFactor: factor})
+		for i := 0; i < 64; i++ {
+			pack.Insert(i, fill(16, 16, colornames.Red))
+		}
+		result, err := pack.PackWithResult()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := noOverlap(pack, 64); err != nil {
+			t.Error(err)
+		}
+		return result.Grows
+	}
+
+	if additive, geometric := grows(0), grows(2); geometric >= additive {
+		t.Errorf("Geometric growth took %d grows, additive took %d", geometric, additive)
+	}
+}
+
+func TestInsertBatch(t *testing.T) {
+	colors := []color.Color{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow, colornames.Purple}
+	items := make(map[int]*image.RGBA)
+	for i, c := range colors {
+		items[i] = fill(4+i, 8, c)
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	if err := pack.InsertBatch(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.InsertBatch(map[int]*image.RGBA{2: fill(1, 1, colornames.Black)}); !errors.Is(err, rectpack.ErrDuplicateId) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrDuplicateId, err)
+	}
+	if err := pack.InsertBatch(map[int]*image.RGBA{5: fill(1, 1, colornames.Black), 6: nil}); !errors.Is(err, rectpack.ErrNilImage) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNilImage, err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if order := pack.InsertionOrder(); len(order) != len(colors) {
+		t.Errorf("Expected %d sprites, Got: %v", len(colors), order)
+	}
+	for i, c := range colors {
+		if err := colorEq(pack.SubImage(i), 4+i, 8, c); err != nil {
+			t.Errorf("%d is not expected: %s", i, err.Error())
+		}
+	}
+}
+
+func TestSplitStrategy(t *testing.T) {
+	sizes := []image.Point{{24, 8}, {16, 8}, {32, 24}, {24, 16}}
+	counts := make(map[rectpack.SplitStrategy]int)
+	for _, strategy := range []rectpack.SplitStrategy{rectpack.ShorterAxis, rectpack.LongerAxis, rectpack.MaximizeLargestRect} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{SplitStrategy: strategy})
+		for i, s := range sizes {
+			pack.Insert(i, fill(s.X, s.Y, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		if err := noOverlap(pack, len(sizes)); err != nil {
+			t.Errorf("%d: %s", strategy, err)
+		}
+		counts[strategy] = len(pack.EmptySpaces())
+	}
+	if counts[rectpack.ShorterAxis] == counts[rectpack.LongerAxis] {
+		t.Errorf("Expected different empty space counts, Got: %d for both", counts[rectpack.ShorterAxis])
+	}
+
+	// every strategy packs arbitrary input, with and without the guillotine algorithm
+	rng := rand.New(rand.NewSource(9))
+	for _, strategy := range []rectpack.SplitStrategy{rectpack.ShorterAxis, rectpack.LongerAxis, rectpack.MaximizeLargestRect} {
+		for _, flags := range []rectpack.CreateFlags{0, rectpack.GuillotineAlgorithm} {
+			for set := 0; set < 200; set++ {
+				pack := rectpack.NewPacker(rectpack.PackerCfg{SplitStrategy: strategy, Flags: flags})
+				n := rng.Intn(25) + 1
+				for i := 0; i < n; i++ {
+					pack.Insert(i, fill(rng.Intn(30)+1, rng.Intn(30)+1, colornames.Red))
+				}
+				if err := pack.Pack(); err != nil {
+					t.Fatalf("strategy %d, flags %d, set %d: %v", strategy, flags, set, err)
+				}
+				if err := noOverlap(pack, n); err != nil {
+					t.Fatalf("strategy %d, flags %d, set %d: %v", strategy, flags, set, err)
+				}
+			}
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	builder := rectpack.NewBuilder().
+		MaxSize(64, 32).
+		Flags(rectpack.FlagNoSort).
+		Flags(rectpack.GuillotineAlgorithm).
+		SplitStrategy(rectpack.LongerAxis).
+		GrowFactor(1.5)
+
+	cfg := builder.Config()
+	if cfg.MaxSize != image.Pt(64, 32) {
+		t.Errorf("MaxSize, Expected: (64,32), Got: %s", cfg.MaxSize)
+	}
+	if cfg.Flags != rectpack.FlagNoSort|rectpack.GuillotineAlgorithm {
+		t.Errorf("Flags, Expected: %d, Got: %d", rectpack.FlagNoSort|rectpack.GuillotineAlgorithm, cfg.Flags)
+	}
+	if cfg.SplitStrategy != rectpack.LongerAxis || cfg.GrowFactor != 1.5 {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+
+	pack := builder.Build()
+	pack.Insert(0, fill(16, 48, colornames.Red))
+	if err := pack.Pack(); !errors.Is(err, rectpack.ErrSpriteTooLarge) {
+		t.Errorf("Expected the built packer to enforce MaxSize, Got: %v", err)
+	}
+
+	builder = rectpack.NewBuilder().MaxSize(64, 32).AllowRotate()
+	if flags := builder.Config().Flags; flags != rectpack.FlagAllowRotate {
+		t.Errorf("Flags, Expected: %d, Got: %d", rectpack.FlagAllowRotate, flags)
+	}
+	pack = builder.Build()
+	pack.Insert(0, fill(16, 48, colornames.Red))
+	if err := pack.Pack(); err != nil || !pack.IsRotated(0) {
+		t.Errorf("Expected the sprite to be rotated to fit, Got: %v", err)
+	}
+}
+
+func TestInsertNil(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	pack.Insert(7, nil)
+	err := pack.Pack()
+	if !errors.Is(err, rectpack.ErrNilImage) {
+		t.Fatalf("Expected: %v, Got: %v", rectpack.ErrNilImage, err)
+	}
+	if !strings.Contains(err.Error(), "id 7") {
+		t.Errorf("Error doesn't name id 7: %v", err)
+	}
+
+	// the other inserters leave nil pictures for Pack to report too
+	for name, insert := range map[string]func(pack *rectpack.Packer){
+		"InsertScaled":     func(pack *rectpack.Packer) { pack.InsertScaled(7, nil, 16) },
+		"InsertAutoRotate": func(pack *rectpack.Packer) { pack.InsertAutoRotate(7, nil) },
+		"InsertFlipped":    func(pack *rectpack.Packer) { pack.InsertFlipped(7, nil, true, false) },
+	} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		insert(pack)
+		if err := pack.Pack(); !errors.Is(err, rectpack.ErrNilImage) {
+			t.Errorf("%s, Expected: %v, Got: %v", name, rectpack.ErrNilImage, err)
+		}
+	}
+}
+
+func TestInsertScaled(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertScaled(0, fill(512, 128, colornames.Red), 256)
+	pack.InsertScaled(1, fill(16, 32, colornames.Blue), 256)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := pack.Get(0).Size(); size != image.Pt(256, 64) {
+		t.Errorf("Expected: (256,64), Got: %s", size)
+	}
+	if err := colorEq(pack.SubImage(0), 256, 64, colornames.Red); err != nil {
+		t.Error(err)
+	}
+	if size := pack.Get(1).Size(); size != image.Pt(16, 32) {
+		t.Errorf("Expected: (16,32), Got: %s", size)
+	}
+}
+
+func TestMipmaps(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(64, 32, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	mips := pack.Mipmaps(0)
+	if len(mips) != 6 {
+		t.Fatalf("Expected 6 levels, Got: %d", len(mips))
+	}
+	prev := pack.Image().Bounds().Size()
+	for i, mip := range mips {
+		size := mip.Bounds().Size()
+		expected := image.Pt((prev.X+1)/2, (prev.Y+1)/2)
+		if size != expected {
+			t.Errorf("Level %d, Expected: %s, Got: %s", i+1, expected, size)
+		}
+		if err := colorEq(mip, size.X, size.Y, colornames.Blue); err != nil {
+			t.Errorf("Level %d: %s", i+1, err)
+		}
+		prev = size
+	}
+
+	if mips := pack.Mipmaps(2); len(mips) != 2 {
+		t.Errorf("Expected 2 levels, Got: %d", len(mips))
+	}
+}
+
+func TestTrim(t *testing.T) {
+	pic := fill(16, 16, color.NRGBA{R: 255, A: 3})
+	for x := 2; x < 14; x++ {
+		for y := 3; y < 13; y++ {
+			pic.Set(x, y, colornames.Red)
+		}
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim, TrimAlphaThreshold: 4})
+	pack.Insert(0, pic)
+	pack.Insert(1, fill(8, 8, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := pack.Get(0).Size(); size != image.Pt(12, 10) {
+		t.Errorf("Packed size, Expected: (12,10), Got: %s", size)
+	}
+	if size := pack.SourceSize(0); size != image.Pt(16, 16) {
+		t.Errorf("Source size, Expected: (16,16), Got: %s", size)
+	}
+	if r := pack.TrimRect(0); r != image.Rect(2, 3, 14, 13) {
+		t.Errorf("Trim rect, Expected: (2,3)-(14,13), Got: %s", r)
+	}
+	if err := colorEq(pack.SubImage(0), 12, 10, colornames.Red); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(1), 8, 8, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+
+	// a blank frame keeps a single transparent pixel instead of failing the pack
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim})
+	pack.Insert(0, image.NewRGBA(image.Rect(0, 0, 16, 16)))
+	pack.Insert(1, fill(8, 8, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if size := pack.Get(0).Size(); size != image.Pt(1, 1) {
+		t.Errorf("Packed size, Expected: (1,1), Got: %s", size)
+	}
+	if size := pack.SourceSize(0); size != image.Pt(16, 16) {
+		t.Errorf("Source size, Expected: (16,16), Got: %s", size)
+	}
+	if err := colorEq(pack.SubImage(0), 1, 1, color.RGBA{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	type anchor struct {
+		Name string
+		X, Y int
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	pack.Insert(1, fill(4, 4, colornames.Blue))
+	pack.InsertAutoRotate(2, fill(4, 12, colornames.Green))
+	pack.SetMeta(1, anchor{Name: "hand", X: 2, Y: 3})
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := pack.Meta(1).(anchor); !ok || m.Name != "hand" {
+		t.Errorf("Unexpected meta: %v", pack.Meta(1))
+	}
+
+	filename := path.Join(t.TempDir(), "atlas.json")
+	if err := pack.SaveMetadata(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved struct {
+		Algorithm string `json:"algorithm"`
+		Version   int    `json:"version"`
+		Frames    []struct {
+			Id         int                      `json:"id"`
+			Frame      struct{ X, Y, W, H int } `json:"frame"`
+			Rotated    bool                     `json:"rotated"`
+			SourceSize struct{ W, H int }       `json:"sourceSize"`
+			Meta       *anchor                  `json:"meta"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Algorithm != pack.AlgorithmID() || saved.Algorithm == "" || saved.Version != rectpack.AlgorithmVersion {
+		t.Errorf("Expected algorithm %q version %d, Got: %q version %d", pack.AlgorithmID(), rectpack.AlgorithmVersion, saved.Algorithm, saved.Version)
+	}
+	if len(saved.Frames) != 3 {
+		t.Fatalf("Expected 3 frames, Got: %d", len(saved.Frames))
+	}
+	if saved.Frames[0].Meta != nil {
+		t.Errorf("Expected no meta for id 0, Got: %v", saved.Frames[0].Meta)
+	}
+	if m := saved.Frames[1].Meta; m == nil || *m != (anchor{Name: "hand", X: 2, Y: 3}) {
+		t.Errorf("Expected the anchor in id 1's frame, Got: %v", m)
+	}
+	if f, r := saved.Frames[1].Frame, pack.Get(1); f.X != r.Min.X || f.Y != r.Min.Y || f.W != r.Dx() || f.H != r.Dy() {
+		t.Errorf("Frame, Expected: %s, Got: %+v", r, f)
+	}
+	if saved.Frames[1].Rotated || !saved.Frames[2].Rotated {
+		t.Errorf("Expected only id 2 to be rotated, Got: %v %v", saved.Frames[1].Rotated, saved.Frames[2].Rotated)
+	}
+	if f := saved.Frames[2]; f.SourceSize.W != 4 || f.SourceSize.H != 12 || f.Frame.W != 12 || f.Frame.H != 4 {
+		t.Errorf("Expected a 4x12 source packed as 12x4, Got: %+v in %+v", f.SourceSize, f.Frame)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(24, 8, colornames.Red))
+	pack.Insert(1, fill(8, 24, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if pack.Bounds() != pack.Image().Bounds() {
+		t.Errorf("Expected: %s, Got: %s", pack.Image().Bounds(), pack.Bounds())
+	}
+	if pack.Size() != pack.Image().Bounds().Size() {
+		t.Errorf("Expected: %s, Got: %s", pack.Image().Bounds().Size(), pack.Size())
+	}
+}
+
+func TestGutterColor(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Padding: 2, GutterColor: colornames.Magenta})
+	pack.Insert(0, fill(16, 16, colornames.Red))
+	pack.Insert(1, fill(8, 8, colornames.Blue))
+	pack.Insert(2, fill(4, 12, colornames.Green))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := noOverlap(pack, 3); err != nil {
+		t.Error(err)
+	}
+
+	img := pack.Image()
+	for i := 0; i < 3; i++ {
+		r := pack.Get(i)
+		gutter := image.Rect(r.Min.X, r.Min.Y, r.Max.X+2, r.Max.Y+2)
+		for y := gutter.Min.Y; y < gutter.Max.Y; y++ {
+			for x := gutter.Min.X; x < gutter.Max.X; x++ {
+				if image.Pt(x, y).In(r) {
+					continue
+				}
+				if got := img.RGBAAt(x, y); got != colornames.Magenta {
+					t.Fatalf("%d gutter at (%d, %d), Expected: %v, Got: %v", i, x, y, colornames.Magenta, got)
+				}
+			}
+		}
+		for j := 0; j < 3; j++ {
+			if other := pack.Get(j); i != j && gutter.Overlaps(other) {
+				t.Errorf("%d's padding overlaps %d", i, j)
+			}
+		}
+	}
+}
+
+func TestSaveStreamed(t *testing.T) {
+	newPack := func() *rectpack.Packer {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Padding: 1, GutterColor: colornames.Magenta})
+		for i, c := range []color.Color{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow, colornames.Orchid} {
+			pack.Insert(i, fill(10+i*7, 30-i*4, c))
+		}
+		return pack
+	}
+	decode := func(filename string) image.Image {
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		img, err := png.Decode(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+
+	dir := t.TempDir()
+	packed := newPack()
+	if err := packed.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := packed.Save(path.Join(dir, "packed.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	streamed := newPack()
+	if err := streamed.SaveStreamed(path.Join(dir, "unplanned.png")); !errors.Is(err, rectpack.ErrNotPlanned) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotPlanned, err)
+	}
+	if _, err := streamed.Plan(); err != nil {
+		t.Fatal(err)
+	}
+	if err := streamed.SaveStreamed(path.Join(dir, "streamed.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, got := decode(path.Join(dir, "packed.png")), decode(path.Join(dir, "streamed.png"))
+	if expected.Bounds() != got.Bounds() {
+		t.Fatalf("Bounds, Expected: %s, Got: %s", expected.Bounds(), got.Bounds())
+	}
+	for y := expected.Bounds().Min.Y; y < expected.Bounds().Max.Y; y++ {
+		for x := expected.Bounds().Min.X; x < expected.Bounds().Max.X; x++ {
+			if c1, c2 := color.NRGBAModel.Convert(expected.At(x, y)), color.NRGBAModel.Convert(got.At(x, y)); c1 != c2 {
+				t.Fatalf("At: (%d, %d), Expected: %v, Got: %v", x, y, c1, c2)
+			}
+		}
+	}
+}
+
+func TestBenchmarkAlgorithms(t *testing.T) {
+	var sprites []*image.RGBA
+	for _, s := range []image.Point{{8, 32}, {32, 16}, {8, 16}, {8, 8}, {24, 24}, {32, 32}} {
+		sprites = append(sprites, fill(s.X, s.Y, colornames.Red))
+	}
+
+	results, err := rectpack.BenchmarkAlgorithms(sprites)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range rectpack.Algorithms() {
+		result, has := results[name]
+		if !has {
+			t.Errorf("No result for %s", name)
+			continue
+		}
+		if result.Size == (image.Point{}) || result.Efficiency <= 0 || result.Efficiency > 1 {
+			t.Errorf("%s has an invalid result: %+v", name, result)
+		}
+	}
+}
+
+func TestInsertAutoRotate(t *testing.T) {
+	pic := fill(4, 8, colornames.Red)
+	for x := 0; x < 4; x++ {
+		for y := 4; y < 8; y++ {
+			pic.Set(x, y, colornames.Blue)
+		}
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertAutoRotate(0, pic)
+	pack.InsertAutoRotate(1, fill(8, 4, colornames.Green))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !pack.IsRotated(0) || pack.IsRotated(1) {
+		t.Errorf("Rotated, Expected: true false, Got: %v %v", pack.IsRotated(0), pack.IsRotated(1))
+	}
+	if size := pack.Get(0).Size(); size != image.Pt(8, 4) {
+		t.Errorf("Expected: (8,4), Got: %s", size)
+	}
+
+	// rotating clockwise moves the top of the sprite to the right
+	img := pack.SubImage(0)
+	if got := img.RGBAAt(0, 0); got != colornames.Blue {
+		t.Errorf("Left, Expected: %v, Got: %v", colornames.Blue, got)
+	}
+	if got := img.RGBAAt(7, 3); got != colornames.Red {
+		t.Errorf("Right, Expected: %v, Got: %v", colornames.Red, got)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	pic := fill(6, 10, color.Transparent)
+	for x := 1; x < 5; x++ {
+		for y := 1; y < 9; y++ {
+			pic.Set(x, y, colornames.Red)
+		}
+	}
+	marker := image.Pt(2, 3)
+	pic.Set(marker.X, marker.Y, colornames.Lime)
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim})
+	pack.Insert(0, fill(12, 12, colornames.Blue))
+	pack.InsertAutoRotate(1, pic)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	offset, rotated := pack.Transform(1)
+	if !rotated {
+		t.Fatal("Expected the sprite to be rotated")
+	}
+	p := image.Pt(pic.Bounds().Dy()-1-marker.Y, marker.X).Add(offset)
+	if !p.In(pack.Get(1)) {
+		t.Fatalf("Mapped point %s is outside of the sprite %s", p, pack.Get(1))
+	}
+	if got := pack.Image().RGBAAt(p.X, p.Y); got != colornames.Lime {
+		t.Errorf("At: %s, Expected: %v, Got: %v", p, colornames.Lime, got)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	rectpack.RegisterFormat(".tex", png.Encode)
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := pack.Save(path.Join(dir, "atlas.unknown")); !errors.Is(err, rectpack.ErrUnsupportedSaveExt) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrUnsupportedSaveExt, err)
+	}
+	filename := path.Join(dir, "atlas.tex")
+	if err := pack.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := colorEq(img, 8, 8, colornames.Red); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSoftMaxSize(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{SoftMaxSize: image.Pt(32, 32)})
+	for i := 0; i < 8; i++ {
+		pack.Insert(i, fill(16, 16, colornames.Red))
+	}
+	result, err := pack.PackWithResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.ExceedsSoftMax {
+		t.Errorf("Expected %s to exceed the soft max size", result.Size)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{SoftMaxSize: image.Pt(32, 32)})
+	pack.Insert(0, fill(16, 16, colornames.Red))
+	if result, err = pack.PackWithResult(); err != nil {
+		t.Fatal(err)
+	}
+	if result.ExceedsSoftMax {
+		t.Errorf("Expected %s to be within the soft max size", result.Size)
+	}
+}
+
+func TestDeterministicLayout(t *testing.T) {
+	newPack := func() *rectpack.Packer {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		for i := 0; i < 24; i++ {
+			// equal areas produce many equal area empty spaces
+			if i%2 == 0 {
+				pack.Insert(i, fill(8, 16, colornames.Red))
+			} else {
+				pack.Insert(i, fill(16, 8, colornames.Blue))
+			}
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		return pack
+	}
+
+	first := newPack()
+	if err := noOverlap(first, 24); err != nil {
+		t.Error(err)
+	}
+	for run := 0; run < 5; run++ {
+		if diff := first.Diff(newPack()); diff != "" {
+			t.Fatalf("Run %d has a different layout: %s", run, diff)
+		}
+	}
+}
+
+func TestPackInto(t *testing.T) {
+	newPack := func() *rectpack.Packer {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		pack.Insert(0, fill(16, 16, colornames.Red))
+		pack.Insert(1, fill(8, 24, colornames.Blue))
+		return pack
+	}
+
+	if err := newPack().PackInto(image.NewRGBA(image.Rect(0, 0, 4, 4))); !errors.Is(err, rectpack.ErrBufferTooSmall) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrBufferTooSmall, err)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	pack := newPack()
+	if err := pack.PackInto(dst); err != nil {
+		t.Fatal(err)
+	}
+	if img := pack.Image(); &img.Pix[0] != &dst.Pix[0] {
+		t.Error("Packer isn't reading from the given buffer")
+	}
+	if pack.Image().Bounds() != pack.Bounds() {
+		t.Errorf("Expected the image to cover %v, Got: %v", pack.Bounds(), pack.Image().Bounds())
+	}
+	if err := colorEq(pack.SubImage(0), 16, 16, colornames.Red); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(1), 8, 24, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+	if r := pack.Get(1); dst.RGBAAt(r.Min.X, r.Min.Y) != colornames.Blue {
+		t.Error("Sprite wasn't drawn into the given buffer")
+	}
+}
+
+func TestKeepSources(t *testing.T) {
+	pic := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(pic, image.Rect(4, 4, 12, 10), image.NewUniform(colornames.Red), image.Point{}, draw.Src)
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim | rectpack.FlagKeepSources})
+	pack.Insert(0, pic)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if src := pack.Source(0); !bytes.Equal(src.Pix, pic.Pix) || src.Rect != pic.Rect {
+		t.Error("Source doesn't match the inserted image")
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, pic)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, rectpack.ErrSourceNotKept) {
+			t.Errorf("Expected: %v, Got: %v", rectpack.ErrSourceNotKept, err)
+		}
+	}()
+	pack.Source(0)
+}
+
+func TestLazyImage(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{
+		Flags:       rectpack.FlagKeepSources,
+		Padding:     1,
+		GutterColor: colornames.Green,
+	})
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 20; i++ {
+		pack.Insert(i, fill(rng.Intn(20)+1, rng.Intn(20)+1, color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), 0, 255}))
+	}
+	if _, err := pack.Plan(); err != nil {
+		t.Fatal(err)
+	}
+	planned := pack.LazyImage()
+	if err := pack.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	img := pack.Image()
+	lazy := pack.LazyImage()
+	b := img.Bounds()
+	for i := 0; i < 500; i++ {
+		x, y := b.Min.X+rng.Intn(b.Dx()), b.Min.Y+rng.Intn(b.Dy())
+		if lazy.At(x, y) != img.At(x, y) || planned.At(x, y) != img.At(x, y) {
+			t.Fatalf("Mismatch at %d,%d: Expected: %v, Got: %v", x, y, img.At(x, y), lazy.At(x, y))
+		}
+	}
+}
+
+func TestTrimSavings(t *testing.T) {
+	pic := image.NewRGBA(image.Rect(0, 0, 10, 8))
+	draw.Draw(pic, image.Rect(2, 1, 8, 7), image.NewUniform(colornames.Red), image.Point{}, draw.Src)
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim})
+	pack.Insert(0, pic)
+	pack.Insert(1, fill(4, 4, colornames.Blue))
+	savings := pack.TrimSavings()
+	if got, want := savings[0], 10*8-6*6; got != want {
+		t.Errorf("Expected: %d, Got: %d", want, got)
+	}
+	if got := savings[1]; got != 0 {
+		t.Errorf("Expected: 0, Got: %d", got)
+	}
+}
+
+func TestStrictErrors(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{StrictErrors: true})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if r := pack.Get(0); !r.Empty() || !errors.Is(pack.Err(), rectpack.ErrNotPacked) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotPacked, pack.Err())
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if r := pack.Get(1); !r.Empty() {
+		t.Errorf("Expected an empty rectangle, Got: %v", r)
+	}
+	if img := pack.SubImage(1); img != nil {
+		t.Errorf("Expected a nil image, Got: %v", img.Bounds())
+	}
+	// the first error sticks
+	if !errors.Is(pack.Err(), rectpack.ErrNotPacked) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotPacked, pack.Err())
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{StrictErrors: true})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	pack.Get(1)
+	if !errors.Is(pack.Err(), rectpack.ErrNotFoundNoDefault) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotFoundNoDefault, pack.Err())
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, rectpack.ErrNotFoundNoDefault) {
+			t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotFoundNoDefault, err)
+		}
+	}()
+	pack.Get(1)
+}
+
+func TestInsertColor(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertColor(0, 4, 4, colornames.Blue)
+	pack.Insert(1, fill(8, 8, colornames.Red))
+
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := colorEq(pack.SubImage(0), 4, 4, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInsertSize(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertSize(0, 16, 8)
+	pack.InsertSize(1, 4, 12)
+	pack.Insert(2, fill(8, 8, colornames.Green))
+
+	if err := pack.Provide(0, fill(16, 8, colornames.Red)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Provide(1, fill(4, 4, colornames.Blue)); !errors.Is(err, rectpack.ErrSizeMismatch) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrSizeMismatch, err)
+	}
+	if err := pack.Provide(2, fill(8, 8, colornames.Blue)); !errors.Is(err, rectpack.ErrNotPlaceholder) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNotPlaceholder, err)
+	}
+	if _, err := pack.Plan(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Commit(); !errors.Is(err, rectpack.ErrMissingPixels) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrMissingPixels, err)
+	}
+	if err := pack.Provide(1, fill(4, 12, colornames.Blue)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[int]struct {
+		w, h int
+		c    color.RGBA
+	}{0: {16, 8, colornames.Red}, 1: {4, 12, colornames.Blue}, 2: {8, 8, colornames.Green}} {
+		if err := colorEq(pack.SubImage(id), want.w, want.h, want.c); err != nil {
+			t.Errorf("id %d: %v", id, err)
+		}
+	}
+
+	// the streamed and lazy images can't read pixels that were never provided either
+	pack = rectpack.NewPacker(rectpack.PackerCfg{StrictErrors: true})
+	pack.InsertSize(0, 4, 4)
+	if _, err := pack.Plan(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.SaveStreamed(path.Join(t.TempDir(), "atlas.png")); !errors.Is(err, rectpack.ErrMissingPixels) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrMissingPixels, err)
+	}
+	if img := pack.LazyImage(); img != nil || !errors.Is(pack.Err(), rectpack.ErrMissingPixels) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrMissingPixels, pack.Err())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	colors := []color.RGBA{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow, colornames.Purple, colornames.Orange}
+	var packs []*rectpack.Packer
+	for p := 0; p < 2; p++ {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		for i := 0; i < 3; i++ {
+			id := p*3 + i
+			pack.Insert(id, fill(8+id*2, 16-id, colors[id]))
+			pack.SetOrigin(id, image.Pt(id, 2))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		packs = append(packs, pack)
+	}
+
+	merged, err := rectpack.Merge(packs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = noOverlap(merged, len(colors)); err != nil {
+		t.Error(err)
+	}
+	for id, c := range colors {
+		if err = colorEq(merged.SubImage(id), 8+id*2, 16-id, c); err != nil {
+			t.Errorf("id %d: %v", id, err)
+		}
+	}
+
+	for id := range colors {
+		if origin := merged.Origin(id); origin != image.Pt(id, 2) {
+			t.Errorf("id %d: Expected origin %v, Got: %v", id, image.Pt(id, 2), origin)
+		}
+	}
+
+	if _, err = rectpack.Merge(packs[0], packs[0]); !errors.Is(err, rectpack.ErrDuplicateId) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrDuplicateId, err)
+	}
+	if _, err = rectpack.Merge(); !errors.Is(err, rectpack.ErrNothingToMerge) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrNothingToMerge, err)
+	}
+}
+
+func TestFits(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(32, 32, colornames.Red))
+	pack.Insert(1, fill(16, 8, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	var largest image.Point
+	for _, space := range pack.EmptySpaces() {
+		if space.Dx() > largest.X {
+			largest.X = space.Dx()
+		}
+		if space.Dy() > largest.Y {
+			largest.Y = space.Dy()
+		}
+	}
+	if !pack.Fits(1, 1) {
+		t.Error("Expected a 1x1 sprite to fit")
+	}
+	if pack.Fits(largest.X+1, largest.Y+1) {
+		t.Errorf("Expected a %dx%d sprite not to fit", largest.X+1, largest.Y+1)
+	}
+}
+
+func TestAlphaLostInJPEG(t *testing.T) {
+	for _, flags := range []rectpack.CreateFlags{0, rectpack.FlagAllowAlphaLoss} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: flags})
+		pack.Insert(0, fill(16, 16, color.RGBA{R: 128, A: 128}))
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"alpha.jpg", "alpha.JPG"} {
+			err := pack.Save(path.Join(t.TempDir(), name))
+			if flags == 0 && !errors.Is(err, rectpack.ErrAlphaLostInJPEG) {
+				t.Errorf("%s: Expected: %v, Got: %v", name, rectpack.ErrAlphaLostInJPEG, err)
+			} else if flags != 0 && err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	// the extension picks the encoder regardless of case
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	filename := path.Join(t.TempDir(), "atlas.PNG")
+	if err := pack.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filename); err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Errorf("Expected a png, Got: %v", err)
+	}
+}
+
+func TestMinSeparation(t *testing.T) {
+	for _, padding := range []int{0, 2, 5} {
+		const sep = 3
+		pack := rectpack.NewPacker(rectpack.PackerCfg{MinSeparation: sep, Padding: padding})
+		rng := rand.New(rand.NewSource(3))
+		for i := 0; i < 30; i++ {
+			pack.Insert(i, fill(rng.Intn(16)+1, rng.Intn(16)+1, colornames.Red))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := sep
+		if padding > want {
+			want = padding
+		}
+		for i := 0; i < 30; i++ {
+			for j := i + 1; j < 30; j++ {
+				a, b := pack.Get(i), pack.Get(j)
+				if a.Max.X+want <= b.Min.X || b.Max.X+want <= a.Min.X || a.Max.Y+want <= b.Min.Y || b.Max.Y+want <= a.Min.Y {
+					continue
+				}
+				t.Errorf("Padding %d: sprites %d %v and %d %v are closer than %d", padding, i, a, j, b, want)
+			}
+		}
+	}
+}
+
+func TestSubImageCopy(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	pack.Insert(1, fill(4, 4, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	img := pack.SubImageCopy(0)
+	if err := colorEq(img, 8, 8, colornames.Red); err != nil {
+		t.Fatal(err)
+	}
+	draw.Draw(img, img.Rect, image.NewUniform(colornames.Green), image.Point{}, draw.Src)
+	if err := colorEq(pack.SubImage(0), 8, 8, colornames.Red); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTrimColor(t *testing.T) {
+	pic := fill(12, 10, colornames.Magenta)
+	draw.Draw(pic, image.Rect(3, 2, 9, 7), image.NewUniform(colornames.Blue), image.Point{}, draw.Src)
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim, TrimColor: colornames.Magenta})
+	pack.Insert(0, pic)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pack.TrimRect(0), image.Rect(3, 2, 9, 7); got != want {
+		t.Errorf("Expected: %v, Got: %v", want, got)
+	}
+	if err := colorEq(pack.SubImage(0), 6, 5, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScanlines(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 10; i++ {
+		pack.Insert(i, fill(rng.Intn(20)+1, rng.Intn(20)+1, color.RGBA{uint8(rng.Intn(256)), 0, uint8(rng.Intn(256)), 255}))
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	img := pack.Image()
+	var (
+		pix   []byte
+		nextY = img.Rect.Min.Y
+	)
+	pack.Scanlines(3)(func(y int, band []byte) bool {
+		if y != nextY {
+			t.Errorf("Expected band at y %d, Got: %d", nextY, y)
+		}
+		pix = append(pix, band...)
+		nextY += 3
+		return true
+	})
+	if !bytes.Equal(pix, img.Pix) {
+		t.Error("Reassembled scanlines don't match the packed image")
+	}
+
+	bands := 0
+	pack.Scanlines(1)(func(int, []byte) bool {
+		bands++
+		return false
+	})
+	if bands != 1 {
+		t.Errorf("Expected iteration to stop after 1 band, Got: %d", bands)
+	}
+}
+
+func TestAllowRotate(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagAllowRotate, MaxSize: image.Pt(32, 64)})
+	pack.Insert(0, fill(48, 16, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if !pack.IsRotated(0) {
+		t.Error("Expected the too wide sprite to be rotated")
+	}
+	if err := colorEq(pack.SubImage(0), 16, 48, colornames.Red); err != nil {
+		t.Error(err)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagAllowRotate, MaxSize: image.Pt(40, 40)})
+	pack.Insert(0, fill(40, 30, colornames.Red))
+	pack.Insert(1, fill(10, 40, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if pack.IsRotated(0) || !pack.IsRotated(1) {
+		t.Errorf("Expected only sprite 1 to be rotated, Got: %v %v", pack.IsRotated(0), pack.IsRotated(1))
+	}
+	if err := noOverlap(pack, 2); err != nil {
+		t.Error(err)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{MaxSize: image.Pt(40, 40)})
+	pack.Insert(0, fill(40, 30, colornames.Red))
+	pack.Insert(1, fill(10, 40, colornames.Blue))
+	if err := pack.Pack(); !errors.Is(err, rectpack.ErrMaxSizeExceeded) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrMaxSizeExceeded, err)
+	}
+
+	// auto rotated sprites keep their landscape orientation even when only the upright one would fit
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagAllowRotate, MaxSize: image.Pt(32, 64)})
+	pack.InsertAutoRotate(0, fill(16, 48, colornames.Red))
+	if err := pack.Pack(); !errors.Is(err, rectpack.ErrSpriteTooLarge) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrSpriteTooLarge, err)
+	}
+
+	// a sprite turned while looking for room is turned back, trim included, when it's skipped
+	tall := image.NewRGBA(image.Rect(0, 0, 14, 22))
+	for y := 2; y < 21; y++ {
+		for x := 1; x < 12; x++ {
+			tall.SetRGBA(x, y, color.RGBA{R: uint8(x * 15), G: uint8(y * 5), A: 255})
+		}
+	}
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagAllowRotate | rectpack.FlagTrim | rectpack.FlagBestEffort, MaxSize: image.Pt(32, 32)})
+	pack.Insert(0, fill(32, 24, colornames.Blue))
+	pack.Insert(1, tall)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if unplaced := pack.Unplaced(); len(unplaced) != 1 || unplaced[0] != 1 {
+		t.Fatalf("Expected id 1 to be skipped, Got: %v", unplaced)
+	}
+	if pack.IsRotated(1) {
+		t.Error("Expected the skipped sprite to be upright")
+	}
+	if want := image.Rect(1, 2, 12, 21); pack.TrimRect(1) != want {
+		t.Errorf("Expected trim: %v, Got: %v", want, pack.TrimRect(1))
+	}
+}
+
+func TestBinaryIndex(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagTrim})
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 12; i++ {
+		pack.Insert(i*3-5, fill(rng.Intn(24)+1, rng.Intn(24)+1, colornames.Red))
+	}
+	trimmed := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(trimmed, image.Rect(2, 2, 6, 6), image.NewUniform(colornames.Blue), image.Point{}, draw.Src)
+	pack.Insert(100, trimmed)
+	pack.InsertAutoRotate(101, fill(4, 12, colornames.Green))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := path.Join(t.TempDir(), "index.bin")
+	if err := pack.SaveBinaryIndex(filename); err != nil {
+		t.Fatal(err)
+	}
+	size, entries, err := rectpack.LoadBinaryIndex(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != pack.Size() {
+		t.Errorf("Expected size: %v, Got: %v", pack.Size(), size)
+	}
+	if len(entries) != 14 {
+		t.Fatalf("Expected 14 entries, Got: %d", len(entries))
+	}
+	for _, e := range entries {
+		if r := pack.Get(e.Id); e.Rect != r {
+			t.Errorf("id %d: Expected: %v, Got: %v", e.Id, r, e.Rect)
+		}
+		if rotated := e.Flags&rectpack.IndexRotated != 0; rotated != pack.IsRotated(e.Id) {
+			t.Errorf("id %d: Expected rotated %v", e.Id, pack.IsRotated(e.Id))
+		}
+		if trimmed := e.Flags&rectpack.IndexTrimmed != 0; trimmed != (e.Id == 100) {
+			t.Errorf("id %d: Unexpected trimmed flag %v", e.Id, trimmed)
+		}
+	}
+
+	// a count the file can't hold is rejected instead of allocated
+	corrupt := path.Join(t.TempDir(), "corrupt.bin")
+	if err := os.WriteFile(corrupt, []byte{8, 0, 0, 0, 8, 0, 0, 0, 0xff, 0xff, 0xff, 0x7f, 1, 2, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rectpack.LoadBinaryIndex(corrupt); !errors.Is(err, rectpack.ErrCorruptIndex) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrCorruptIndex, err)
+	}
+
+	// positions are absolute, so the edges include the offset and negative positions can't be stored
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Offset: image.Pt(100, 50)})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.SaveBinaryIndex(filename); err != nil {
+		t.Fatal(err)
+	}
+	if size, entries, err := rectpack.LoadBinaryIndex(filename); err != nil || size != pack.Bounds().Max || entries[0].Rect != pack.Get(0) {
+		t.Errorf("Expected edges %v and %v, Got: %v %v %v", pack.Bounds().Max, pack.Get(0), size, entries, err)
+	}
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Offset: image.Pt(-4, 0)})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.SaveBinaryIndex(filename); !errors.Is(err, rectpack.ErrIndexOverflow) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrIndexOverflow, err)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	offset := image.Pt(100, 50)
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Offset: offset})
+	rng := rand.New(rand.NewSource(13))
+	for i := 0; i < 15; i++ {
+		pack.Insert(i, fill(rng.Intn(20)+1, rng.Intn(20)+1, colornames.Red))
+	}
+	pack.Insert(15, fill(6, 6, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pack.Bounds().Min; got != offset {
+		t.Errorf("Expected bounds to start at %v, Got: %v", offset, got)
+	}
+	for i := 0; i < 16; i++ {
+		if r := pack.Get(i); r.Min.X < offset.X || r.Min.Y < offset.Y {
+			t.Errorf("id %d: %v is outside of the offset %v", i, r, offset)
+		}
+	}
+	if err := noOverlap(pack, 16); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(15), 6, 6, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{Offset: offset, StrictErrors: true})
+	pack.Insert(0, fill(6, 6, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if img := pack.SubImage(9); img != nil || !errors.Is(pack.Err(), rectpack.ErrNotFoundNoDefault) {
+		t.Errorf("Expected a nil image and %v, Got: %v %v", rectpack.ErrNotFoundNoDefault, img, pack.Err())
+	}
+
+	// an empty sprite still has a rect, so it gets an empty image rather than nil
+	pack = rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(0, 6, colornames.Blue))
+	pack.Insert(1, fill(6, 6, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if img := pack.SubImage(0); img == nil || !img.Bounds().Empty() {
+		t.Errorf("Expected an empty image, Got: %v", img)
+	}
+}
+
+func TestOnPlace(t *testing.T) {
+	placed := make(map[int]image.Rectangle)
+	pack := rectpack.NewPacker(rectpack.PackerCfg{
+		OnPlace: func(id int, space, r image.Rectangle) {
+			if !r.In(space) {
+				t.Errorf("id %d: %v isn't inside of its space %v", id, r, space)
+			}
+			placed[id] = r
+		},
+	})
+	rng := rand.New(rand.NewSource(17))
+	for i := 0; i < 20; i++ {
+		pack.Insert(i, fill(rng.Intn(20)+1, rng.Intn(20)+1, colornames.Red))
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(placed) != 20 {
+		t.Errorf("Expected 20 placements, Got: %d", len(placed))
+	}
+	for id, r := range placed {
+		if got := pack.Get(id); got != r {
+			t.Errorf("id %d: Expected final placement %v, Got: %v", id, got, r)
+		}
+	}
+}
+
+func TestSaveGIF(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	pack.Insert(1, fill(4, 12, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := path.Join(t.TempDir(), "atlas.gif")
+	if err := pack.SaveGIF(filename, 50); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 1 || anim.Delay[0] != 50 {
+		t.Fatalf("Expected 1 frame with a delay of 50, Got: %d frames, delays %v", len(anim.Image), anim.Delay)
+	}
+	r := pack.Get(1)
+	if got := color.RGBAModel.Convert(anim.Image[0].At(r.Min.X, r.Min.Y)); got != colornames.Blue {
+		t.Errorf("Expected: %v, Got: %v", colornames.Blue, got)
+	}
+}
+
+func TestPackBestUntil(t *testing.T) {
+	newPack := func() *rectpack.Packer {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		rng := rand.New(rand.NewSource(19))
+		for i := 0; i < 30; i++ {
+			pack.Insert(i, fill(rng.Intn(30)+1, rng.Intn(30)+1, colornames.Red))
+		}
+		return pack
+	}
+
+	baseline, err := newPack().PackWithResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, deadline := range []time.Time{time.Now().Add(-time.Second), time.Now().Add(20 * time.Millisecond)} {
+		pack := newPack()
+		result, err := pack.PackBestUntil(deadline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = noOverlap(pack, 30); err != nil {
+			t.Error(err)
+		}
+		if result.Size != pack.Size() {
+			t.Errorf("Result size %v doesn't match the atlas size %v", result.Size, pack.Size())
+		}
+		if result.Size.X*result.Size.Y > baseline.Size.X*baseline.Size.Y {
+			t.Errorf("Expected an atlas no larger than %v, Got: %v", baseline.Size, result.Size)
+		}
+	}
+}
+
+func TestTint(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.White))
+	pack.Insert(1, fill(8, 8, colornames.White))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	pack.Tint(0, color.RGBA{R: 128, A: 128})
+	if err := colorEq(pack.SubImage(0), 8, 8, color.RGBA{R: 128, A: 128}); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(1), 8, 8, colornames.White); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMinSize(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{MinSize: image.Pt(64, 32)})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	pack.Insert(1, fill(2, 6, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := pack.Image().Bounds().Size(); size.X < 64 || size.Y < 32 {
+		t.Errorf("Expected at least 64x32, Got: %v", size)
+	}
+	if err := colorEq(pack.SubImage(0), 4, 4, colornames.Red); err != nil {
+		t.Error(err)
+	}
+	if !pack.Fits(50, 30) {
+		t.Error("Expected the padding to be usable empty space")
+	}
+	if err := noOverlap(pack, 2); err != nil {
+		t.Error(err)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{
+		Flags:       rectpack.FlagFixedWidth,
+		InitialSize: image.Pt(16, 0),
+		MaxSize:     image.Pt(32, 32),
+		MinSize:     image.Pt(64, 64),
+	})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if size := pack.Bounds().Size(); size != image.Pt(16, 32) {
+		t.Errorf("Expected padding to keep the fixed width and MaxSize 16x32, Got: %v", size)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{InitialSize: image.Pt(64, 64), MaxSize: image.Pt(32, 48)})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if size := pack.Bounds().Size(); size != image.Pt(32, 48) {
+		t.Errorf("Expected InitialSize to be reduced to MaxSize 32x48, Got: %v", size)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	outputs := make(map[rectpack.PixelFormat]image.Image)
+	for _, format := range []rectpack.PixelFormat{rectpack.FormatRGBA, rectpack.FormatNRGBA} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Format: format})
+		pack.Insert(0, fill(8, 8, color.NRGBA{R: 200, G: 100, B: 50, A: 128}))
+		pack.Insert(1, fill(4, 12, colornames.Blue))
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		outputs[format] = pack.Output()
+	}
+
+	if _, ok := outputs[rectpack.FormatRGBA].(*image.RGBA); !ok {
+		t.Errorf("Expected *image.RGBA, Got: %T", outputs[rectpack.FormatRGBA])
+	}
+	nrgba, ok := outputs[rectpack.FormatNRGBA].(*image.NRGBA)
+	if !ok {
+		t.Fatalf("Expected *image.NRGBA, Got: %T", outputs[rectpack.FormatNRGBA])
+	}
+	rgba := outputs[rectpack.FormatRGBA]
+	if rgba.Bounds() != nrgba.Bounds() {
+		t.Fatalf("Expected: %v, Got: %v", rgba.Bounds(), nrgba.Bounds())
+	}
+	b := rgba.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// converting to and from non premultiplied color may round each channel by one
+			r1, g1, b1, a1 := rgba.At(x, y).RGBA()
+			r2, g2, b2, a2 := nrgba.At(x, y).RGBA()
+			for _, d := range []int{int(r1) - int(r2), int(g1) - int(g2), int(b1) - int(b2), int(a1) - int(a2)} {
+				if d > 0x101 || d < -0x101 {
+					t.Fatalf("At (%d, %d): Expected: %v, Got: %v", x, y, rgba.At(x, y), nrgba.At(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestParallelism(t *testing.T) {
+	images := make(map[int]*image.RGBA)
+	for _, workers := range []int{1, 4} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{Parallelism: workers})
+		rng := rand.New(rand.NewSource(23))
+		for i := 0; i < 50; i++ {
+			pack.Insert(i, fill(rng.Intn(20)+1, rng.Intn(20)+1, color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+		images[workers] = pack.Image()
+	}
+
+	if serial, parallel := images[1], images[4]; serial.Bounds() != parallel.Bounds() || !bytes.Equal(serial.Pix, parallel.Pix) {
+		t.Error("Parallel texture doesn't match the serial texture")
+	}
+}
+
+func BenchmarkParallelism(b *testing.B) {
+	sprites := make([]*image.RGBA, 256)
+	for i := range sprites {
+		sprites[i] = fill(64, 64, colornames.Red)
+	}
+	for _, workers := range []int{1, 4} {
+		b.Run(strconv.Itoa(workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				pack := rectpack.NewPacker(rectpack.PackerCfg{Parallelism: workers})
+				for i, pic := range sprites {
+					pack.Insert(i, pic)
+				}
+				if err := pack.Pack(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStrictCounts(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagStrictCounts})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	pack.Insert(1, fill(4, 4, colornames.Green))
+	pack.Insert(1, fill(8, 8, colornames.Blue))
+	err := pack.Pack()
+	if !errors.Is(err, rectpack.ErrDroppedSprites) {
+		t.Fatalf("Expected: %v, Got: %v", rectpack.ErrDroppedSprites, err)
+	}
+	if !strings.Contains(err.Error(), "[1]") {
+		t.Errorf("Expected the error to name id 1, Got: %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	pack.Insert(1, fill(4, 12, colornames.Blue))
+	state := pack.Snapshot()
+
+	pack.Insert(2, fill(16, 16, colornames.Green))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	pack.Restore(state)
+	if pack.Packed() {
+		t.Error("Restored packer shouldn't be packed")
+	}
+	if order := pack.InsertionOrder(); fmt.Sprint(order) != fmt.Sprint([]int{0, 1}) {
+		t.Errorf("Expected: [0 1], Got: %v", order)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := pack.GetOr(2); found {
+		t.Error("Sprite inserted after the snapshot is still packed")
+	}
+
+	// packing from the same snapshot is reproducible
+	first := pack.Image()
+	pack.Restore(state)
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Pix, pack.Image().Pix) || first.Bounds() != pack.Bounds() {
+		t.Error("Packing a restored snapshot twice gave different results")
+	}
+}
+
+func TestDrawImage(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	pack.Insert(1, fill(8, 8, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	draw.Draw(pack.DrawImage(), pack.Get(0), image.NewUniform(colornames.Green), image.Point{}, draw.Src)
+	if err := colorEq(pack.SubImage(0), 8, 8, colornames.Green); err != nil {
+		t.Error(err)
+	}
+	if err := colorEq(pack.SubImage(1), 8, 8, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueuedArea(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(4, 4, colornames.Red))
+	pack.Insert(1, fill(8, 2, colornames.Green))
+	pack.Insert(2, fill(3, 5, colornames.Blue))
+
+	if count := pack.QueuedCount(); count != 3 {
+		t.Errorf("Expected: 3, Got: %d", count)
+	}
+	if total := pack.QueuedArea(); total != 16+16+15 {
+		t.Errorf("Expected: %d, Got: %d", 16+16+15, total)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if total := pack.QueuedArea(); total != 0 {
+		t.Errorf("Expected: 0 after packing, Got: %d", total)
+	}
+}
+
+func TestFindStrategy(t *testing.T) {
+	for name, strategy := range map[string]rectpack.FindStrategy{
+		"FirstFit":         rectpack.FirstFit,
+		"BestAreaFit":      rectpack.BestAreaFit,
+		"BestShortSideFit": rectpack.BestShortSideFit,
+		"BestLongSideFit":  rectpack.BestLongSideFit,
+	} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{FindStrategy: strategy})
+		rng := rand.New(rand.NewSource(29))
+		for i := 0; i < 40; i++ {
+			pack.Insert(i, fill(rng.Intn(32)+1, rng.Intn(32)+1, colornames.Red))
+		}
+		result, err := pack.PackWithResult()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err = noOverlap(pack, 40); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		t.Logf("%s: %v, %.2f efficient", name, result.Size, result.Efficiency)
+	}
+}
+
+func TestGetPivotRect(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(16, 8, colornames.Red))
+	pack.Insert(1, fill(4, 4, colornames.Blue))
+	pack.SetOrigin(0, image.Pt(8, 4))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, pivot := pack.GetPivotRect(0)
+	if r != pack.Get(0) {
+		t.Errorf("Expected: %v, Got: %v", pack.Get(0), r)
+	}
+	if pivot != [2]float64{0.5, 0.5} {
+		t.Errorf("Expected: [0.5 0.5], Got: %v", pivot)
+	}
+	if _, pivot = pack.GetPivotRect(1); pivot != [2]float64{0, 0} {
+		t.Errorf("Expected: [0 0], Got: %v", pivot)
+	}
+
+	// the pivot is relative to the upright source, not the rotated sprite
+	pack = rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertAutoRotate(0, fill(4, 12, colornames.Red))
+	pack.SetOrigin(0, image.Pt(2, 9))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if size := pack.SourceSize(0); size != image.Pt(4, 12) {
+		t.Errorf("Source size, Expected: (4,12), Got: %s", size)
+	}
+	if _, pivot = pack.GetPivotRect(0); pivot != [2]float64{0.5, 0.75} {
+		t.Errorf("Expected: [0.5 0.75], Got: %v", pivot)
+	}
+}
+
+func TestInsertFromFileFormat(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "sprite.dat")
+	if err := os.WriteFile(filename, encodePNG(t, fill(6, 4, colornames.Red)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unknown := path.Join(dir, "sprite.webp")
+	if err := os.WriteFile(unknown, []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	if err := pack.InsertFromFileFormat(0, filename, "PNG"); err != nil {
+		t.Fatal(err)
+	}
+	err := pack.InsertFromFileFormat(1, filename, "webp")
+	if !errors.Is(err, rectpack.ErrUnknownFormat) || !strings.Contains(err.Error(), "gif, jpeg, jpg, png") {
+		t.Errorf("Expected an explained %v, Got: %v", rectpack.ErrUnknownFormat, err)
+	}
+	err = pack.InsertFromFile(2, unknown)
+	if !errors.Is(err, image.ErrFormat) || !strings.Contains(err.Error(), "gif, jpeg, jpg, png") {
+		t.Errorf("Expected an explained %v, Got: %v", image.ErrFormat, err)
+	}
+	missing := path.Join(dir, "missing.png")
+	if err = pack.InsertFromFile(3, missing); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), missing) {
+		t.Errorf("Expected a not exist error naming %s, Got: %v", missing, err)
+	}
+	if err = pack.InsertFromFileFormat(3, missing, "png"); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), missing) {
+		t.Errorf("Expected a not exist error naming %s, Got: %v", missing, err)
+	}
+
+	if err = pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err = colorEq(pack.SubImage(0), 6, 4, colornames.Red); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestByPosition(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	rng := rand.New(rand.NewSource(31))
+	for i := 0; i < 15; i++ {
+		pack.Insert(i, fill(rng.Intn(16)+1, rng.Intn(16)+1, colornames.Red))
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := pack.ByPosition()
+	if len(ids) != 15 {
+		t.Fatalf("Expected 15 ids, Got: %v", ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		a, b := pack.Get(ids[i-1]).Min, pack.Get(ids[i]).Min
+		if a.Y > b.Y || (a.Y == b.Y && a.X > b.X) {
+			t.Errorf("Sprite %d at %v comes before sprite %d at %v", ids[i-1], a, ids[i], b)
+		}
+	}
+}
+
+func TestContentBounds(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{GrowFactor: 2})
+	pack.Insert(0, fill(10, 10, colornames.Red))
+	pack.Insert(1, fill(10, 6, colornames.Blue))
+	pack.Insert(2, fill(3, 3, colornames.Green))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := pack.Get(0).Union(pack.Get(1)).Union(pack.Get(2))
+	if got := pack.ContentBounds(); got != want {
+		t.Errorf("Expected: %v, Got: %v", want, got)
+	}
+	if want == pack.Bounds() {
+		t.Errorf("Expected growing to leave an empty margin around %v", want)
+	}
+}
+
+func TestSaveAtomic(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	filename := path.Join(dir, "atlas.bad")
+	if err := os.WriteFile(filename, []byte("previous atlas"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Save(filename); !errors.Is(err, rectpack.ErrUnsupportedSaveExt) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrUnsupportedSaveExt, err)
+	}
+	if data, err := os.ReadFile(filename); err != nil || string(data) != "previous atlas" {
+		t.Errorf("Existing file was changed by a failed save: %q, %v", data, err)
+	}
+
+	filename = path.Join(dir, "atlas.png")
+	if err := os.WriteFile(filename, []byte("previous atlas"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filename, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filename); err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Errorf("Expected the existing file to be replaced by a png, Got: %v", err)
+	}
+	if info, err := os.Stat(filename); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the replaced file to keep its permissions, Got: %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files to be left behind, Got: %d entries", len(entries))
+	}
+
+	// the other formats are written the same way, metadata that can't be marshaled fails part way through
+	filename = path.Join(dir, "atlas.json")
+	if err := os.WriteFile(filename, []byte("previous metadata"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pack.SetMeta(0, make(chan int))
+	if err := pack.SaveMetadata(filename); err == nil {
+		t.Error("Expected unmarshalable metadata to fail")
+	}
+	if data, err := os.ReadFile(filename); err != nil || string(data) != "previous metadata" {
+		t.Errorf("Existing file was changed by a failed save: %q, %v", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("Expected no temporary files to be left behind, Got: %d entries", len(entries))
+	}
+
+	filename = path.Join(dir, "new.png")
+	if err := pack.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filename); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0644 {
+		t.Errorf("Expected a new file to be 0644, Got: %v", info.Mode().Perm())
+	}
+}
+
+func TestCellSize(t *testing.T) {
+	colors := []color.RGBA{colornames.Red, colornames.Green, colornames.Blue, colornames.Yellow, colornames.Purple}
+	for _, centered := range []bool{false, true} {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{CellSize: image.Pt(20, 20), CellCentered: centered})
+		for i, c := range colors {
+			pack.Insert(i, fill(16, 16, c))
+		}
+		if err := pack.Pack(); err != nil {
+			t.Fatal(err)
+		}
+
+		if size := pack.Size(); size != image.Pt(60, 40) {
+			t.Errorf("Expected a 3x2 grid of 60x40, Got: %v", size)
+		}
+		var offset image.Point
+		if centered {
+			offset = image.Pt(2, 2)
+		}
+		for i, c := range colors {
+			r := pack.Get(i)
+			if r.Min.X%20 != 0 || r.Min.Y%20 != 0 || r.Size() != image.Pt(20, 20) {
+				t.Errorf("id %d: %v isn't a grid cell", i, r)
+			}
+			at := r.Min.Add(offset)
+			if got := pack.Image().RGBAAt(at.X, at.Y); got != c {
+				t.Errorf("id %d: Expected %v at %v, Got: %v", i, c, at, got)
+			}
+		}
+	}
+
+	// cells are adjacent, so no gutter is painted over the neighbouring sprites
+	pack := rectpack.NewPacker(rectpack.PackerCfg{CellSize: image.Pt(8, 8), Padding: 2, GutterColor: colornames.Magenta, Flags: rectpack.FlagKeepSources})
+	for i := 0; i < 4; i++ {
+		pack.Insert(i, fill(8, 8, colornames.Red))
+	}
+	if _, err := pack.Plan(); err != nil {
+		t.Fatal(err)
+	}
+	lazy := pack.LazyImage()
+	if err := pack.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		r := pack.Get(i)
+		if err := colorEq(pack.SubImage(i), 8, 8, colornames.Red); err != nil {
+			t.Errorf("id %d: %v", i, err)
+		}
+		if got := lazy.At(r.Min.X, r.Min.Y); got != colornames.Red {
+			t.Errorf("id %d: Expected the lazy image to be %v at %v, Got: %v", i, colornames.Red, r.Min, got)
+		}
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{CellSize: image.Pt(8, 8)})
+	pack.Insert(0, fill(16, 4, colornames.Red))
+	if err := pack.Pack(); !errors.Is(err, rectpack.ErrSpriteExceedsCell) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrSpriteExceedsCell, err)
+	}
+}
+
+func TestPackAndSlice(t *testing.T) {
+	colors := []color.RGBA{colornames.Red, colornames.Green, colornames.Blue}
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	for i, c := range colors {
+		pack.Insert(i, fill(4+i*3, 10-i*2, c))
+	}
+
+	sprites, err := pack.PackAndSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sprites) != len(colors) {
+		t.Fatalf("Expected %d sprites, Got: %d", len(colors), len(sprites))
+	}
+	for i, c := range colors {
+		if err = colorEq(sprites[i], 4+i*3, 10-i*2, c); err != nil {
+			t.Errorf("id %d: %v", i, err)
+		}
+	}
+}
+
+func TestAlgorithmID(t *testing.T) {
+	for want, cfg := range map[string]rectpack.PackerCfg{
+		"split/shorter-axis/first-fit":               {},
+		"guillotine/longer-axis/best-short-side-fit": {Flags: rectpack.GuillotineAlgorithm, SplitStrategy: rectpack.LongerAxis, FindStrategy: rectpack.BestShortSideFit},
+		"grid": {CellSize: image.Pt(8, 8)},
+	} {
+		if got := rectpack.NewPacker(cfg).AlgorithmID(); got != want {
+			t.Errorf("Expected: %q, Got: %q", want, got)
+		}
+	}
+}
+
+func TestInsertCrop(t *testing.T) {
+	img := fill(20, 10, colornames.Red)
+	draw.Draw(img, image.Rect(10, 0, 20, 10), image.NewUniform(colornames.Blue), image.Point{}, draw.Src)
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	if err := pack.InsertCrop(0, img, image.Rect(10, 0, 20, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.InsertCrop(1, img, image.Rect(15, 0, 25, 10)); !errors.Is(err, rectpack.ErrCropOutOfBounds) {
+		t.Errorf("Expected: %v, Got: %v", rectpack.ErrCropOutOfBounds, err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := colorEq(pack.SubImage(0), 10, 10, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSaveFunc(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := path.Join(t.TempDir(), "atlas.custom")
+	err := pack.SaveFunc(filename, func(w io.Writer, img *image.RGBA) error {
+		if _, err := io.WriteString(w, "ATLAS"); err != nil {
+			return err
+		}
+		return png.Encode(w, img)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("ATLAS")) {
+		t.Fatalf("Expected the sentinel header, Got: %q", data[:5])
+	}
+	img, err := png.Decode(bytes.NewReader(data[5:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = colorEq(img, 8, 8, colornames.Red); err != nil {
+		t.Error(err)
+	}
+
+	sentinel := errors.New("encoder failed")
+	if err = pack.SaveFunc(filename, func(io.Writer, *image.RGBA) error { return sentinel }); !errors.Is(err, sentinel) {
+		t.Errorf("Expected: %v, Got: %v", sentinel, err)
+	}
+	if after, _ := os.ReadFile(filename); !bytes.Equal(after, data) {
+		t.Error("Failed save changed the existing file")
+	}
+}
+
+func TestConcurrentPack(t *testing.T) {
+	for run := 0; run < 20; run++ {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{})
+		for i := 0; i < 20; i++ {
+			pack.Insert(i, fill(16, 16, colornames.Red))
+		}
+
+		var (
+			start = make(chan struct{})
+			errs  = make(chan error, 2)
+		)
+		for i := 0; i < 2; i++ {
+			go func() {
+				<-start
+				errs <- pack.Pack()
+			}()
+		}
+		close(start)
+
+		succeeded := 0
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err == nil {
+				succeeded++
+			} else if !errors.Is(err, rectpack.ErrAlreadyPacked) {
+				t.Fatalf("Expected: %v, Got: %v", rectpack.ErrAlreadyPacked, err)
+			}
+		}
+		if succeeded != 1 {
+			t.Fatalf("Expected exactly one Pack to succeed, Got: %d", succeeded)
+		}
+		if err := noOverlap(pack, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSubImageUpright(t *testing.T) {
+	tall := image.NewRGBA(image.Rect(0, 0, 4, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 4; x++ {
+			tall.SetRGBA(x, y, color.RGBA{R: uint8(x * 60), G: uint8(y * 25), A: 255})
+		}
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.InsertAutoRotate(0, tall)
+	pack.Insert(1, fill(3, 3, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+	if !pack.IsRotated(0) {
+		t.Fatal("Expected the tall sprite to be rotated")
+	}
+
+	upright := pack.SubImageUpright(0)
+	if upright.Bounds() != tall.Bounds() || !bytes.Equal(upright.Pix, tall.Pix) {
+		t.Error("Upright subimage doesn't match the original")
+	}
+	if err := colorEq(pack.SubImageUpright(1), 3, 3, colornames.Blue); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDPI(t *testing.T) {
+	saves := map[string]func(*rectpack.Packer, string) error{
+		"Save": func(pack *rectpack.Packer, filename string) error {
+			if err := pack.Pack(); err != nil {
+				return err
+			}
+			return pack.Save(filename)
+		},
+		"SavePaletted": func(pack *rectpack.Packer, filename string) error {
+			if err := pack.Pack(); err != nil {
+				return err
+			}
+			_, err := pack.SavePaletted(filename, 16)
+			return err
+		},
+		"SaveStreamed": func(pack *rectpack.Packer, filename string) error {
+			if _, err := pack.Plan(); err != nil {
+				return err
+			}
+			return pack.SaveStreamed(filename)
+		},
+	}
+	for name, save := range saves {
+		pack := rectpack.NewPacker(rectpack.PackerCfg{DPI: 144})
+		pack.Insert(0, fill(8, 8, colornames.Red))
+
+		filename := path.Join(t.TempDir(), "atlas.png")
+		if err := save(pack, filename); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = png.Decode(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		found := false
+		for rest := data[8:]; len(rest) >= 12; {
+			n := binary.BigEndian.Uint32(rest)
+			kind, body := string(rest[4:8]), rest[8:8+n]
+			if kind == "pHYs" {
+				found = true
+				x, y := binary.BigEndian.Uint32(body), binary.BigEndian.Uint32(body[4:])
+				if want := uint32(math.Round(144 / 0.0254)); x != want || y != want || body[8] != 1 {
+					t.Errorf("%s: Expected %d pixels per meter, Got: %d, %d, unit %d", name, want, x, y, body[8])
+				}
+			}
+			rest = rest[12+n:]
+		}
+		if !found {
+			t.Errorf("%s: Expected a pHYs chunk", name)
+		}
+	}
+}
+
+func TestMemoryBytes(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(0, fill(10, 6, colornames.Red))
+	pack.Insert(1, fill(3, 12, colornames.Blue))
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	size := pack.Size()
+	if got, want := pack.MemoryBytes(), 4*size.X*size.Y; got != want {
+		t.Errorf("Expected: %d, Got: %d", want, got)
+	}
+}
+
+func TestRequireOpaque(t *testing.T) {
+	pack := rectpack.NewPacker(rectpack.PackerCfg{Flags: rectpack.FlagRequireOpaque})
+	pack.Insert(0, fill(8, 8, colornames.Red))
+	translucent := fill(8, 8, colornames.Blue)
+	translucent.SetRGBA(3, 3, color.RGBA{B: 100, A: 100})
+	pack.Insert(1, translucent)
+
+	err := pack.Pack()
+	if !errors.Is(err, rectpack.ErrNotOpaque) {
+		t.Fatalf("Expected: %v, Got: %v", rectpack.ErrNotOpaque, err)
+	}
+	if !strings.Contains(err.Error(), "[1]") {
+		t.Errorf("Expected the error to name id 1, Got: %v", err)
+	}
+
+	pack = rectpack.NewPacker(rectpack.PackerCfg{})
+	pack.Insert(1, translucent)
+	if err = pack.Pack(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOnStep(t *testing.T) {
+	var steps []int
+	pack := rectpack.NewPacker(rectpack.PackerCfg{
+		OnStep: func(i, count int) {
+			if i != len(steps) {
+				t.Errorf("Expected step %d, Got: %d", len(steps), i)
+			}
+			steps = append(steps, count)
+		},
+	})
+	rng := rand.New(rand.NewSource(37))
+	for i := 0; i < 25; i++ {
+		pack.Insert(i, fill(rng.Intn(20)+1, rng.Intn(20)+1, colornames.Red))
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(steps) != 25 {
+		t.Fatalf("Expected 25 steps, Got: %d", len(steps))
+	}
+	if last := steps[len(steps)-1]; last != len(pack.EmptySpaces()) {
+		t.Errorf("Expected the last step to report %d empty spaces, Got: %d", len(pack.EmptySpaces()), last)
+	}
+}
@@ -0,0 +1,77 @@
+package rectpack
+
+import "image"
+
+// A copy of a packer's state taken by Snapshot, to be handed back to Restore
+type PackerState struct {
+	pack Packer
+}
+
+// Captures the packer's queued sprites, placements, texture and configuration so that it can be restored later.
+// The source images themselves are shared, not copied, so they shouldn't be modified while a snapshot refers to them.
+func (pack *Packer) Snapshot() PackerState {
+	return PackerState{pack: pack.clone()}
+}
+
+// Puts the packer back into the state it was in when the snapshot was taken, the snapshot can be restored again
+func (pack *Packer) Restore(state PackerState) {
+	*pack = state.pack.clone()
+}
+
+// Helper to copy the packer so that changes to the copy don't affect the original
+func (pack *Packer) clone() (c Packer) {
+	c = *pack
+	c.emptySpaces = append([]image.Rectangle{}, pack.emptySpaces...)
+	c.queued = append([]queuedData{}, pack.queued...)
+	c.unplaced = append([]int(nil), pack.unplaced...)
+	c.order = append([]int(nil), pack.order...)
+	c.rects = copyRects(pack.rects)
+	c.trims = copyRects(pack.trims)
+	c.rotated = copyBools(pack.rotated)
+	c.pending = copyBools(pack.pending)
+
+	c.images = make(map[int]*image.RGBA, len(pack.images))
+	for id, pic := range pack.images {
+		c.images[id] = pic
+	}
+	c.sizes = make(map[int]image.Point, len(pack.sizes))
+	for id, size := range pack.sizes {
+		c.sizes[id] = size
+	}
+	c.origins = make(map[int]image.Point, len(pack.origins))
+	for id, origin := range pack.origins {
+		c.origins[id] = origin
+	}
+	c.meta = make(map[int]interface{}, len(pack.meta))
+	for id, v := range pack.meta {
+		c.meta[id] = v
+	}
+	c.files = make(map[int]SourceFile, len(pack.files))
+	for id, file := range pack.files {
+		c.files[id] = file
+	}
+	if pack.pic != nil {
+		c.pic = &image.RGBA{
+			Pix:    append([]uint8(nil), pack.pic.Pix...),
+			Stride: pack.pic.Stride,
+			Rect:   pack.pic.Rect,
+		}
+	}
+	return
+}
+
+func copyRects(m map[int]image.Rectangle) map[int]image.Rectangle {
+	c := make(map[int]image.Rectangle, len(m))
+	for id, r := range m {
+		c[id] = r
+	}
+	return c
+}
+
+func copyBools(m map[int]bool) map[int]bool {
+	c := make(map[int]bool, len(m))
+	for id, b := range m {
+		c[id] = b
+	}
+	return c
+}
@@ -0,0 +1,18 @@
+module github.com/dusk125/rectpack/svg
+
+go 1.17
+
+require (
+	github.com/dusk125/rectpack v0.0.0-20261015095539-0ebb02260a39
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+)
+
+require (
+	golang.org/x/image v0.0.0-20211028202545-6944b10bf410 // indirect
+	golang.org/x/net v0.0.0-20211118161319-6a13c67c3ce4 // indirect
+	golang.org/x/text v0.3.6 // indirect
+)
+
+// builds against the rectpack checkout this module lives in, dependents use the required version
+replace github.com/dusk125/rectpack => ../
@@ -0,0 +1,37 @@
+// Package svg rasterizes SVG files for packing. It is its own module so the SVG dependency stays out of rectpack's module graph
+package svg
+
+import (
+	"image"
+	"math"
+
+	"github.com/dusk125/rectpack"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// Rasterizes the SVG file to a w by h image and inserts it into the packer.
+// If either w or h is zero it's derived from the other using the SVG's aspect ratio, if both are zero the SVG's own size is used.
+func InsertSVG(pack *rectpack.Packer, id int, filename string, w, h int) (err error) {
+	icon, err := oksvg.ReadIcon(filename, oksvg.WarnErrorMode)
+	if err != nil {
+		return
+	}
+
+	vw, vh := icon.ViewBox.W, icon.ViewBox.H
+	switch {
+	case w == 0 && h == 0:
+		w, h = int(math.Ceil(vw)), int(math.Ceil(vh))
+	case w == 0 && vh > 0:
+		w = int(math.Round(float64(h) * vw / vh))
+	case h == 0 && vw > 0:
+		h = int(math.Round(float64(w) * vh / vw))
+	}
+
+	pic := image.NewRGBA(image.Rect(0, 0, w, h))
+	icon.SetTarget(0, 0, float64(w), float64(h))
+	scanner := rasterx.NewScannerGV(w, h, pic, pic.Bounds())
+	icon.Draw(rasterx.NewDasher(w, h, scanner), 1)
+	pack.Insert(id, pic)
+	return
+}
@@ -0,0 +1,40 @@
+package svg_test
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dusk125/rectpack"
+	"github.com/dusk125/rectpack/svg"
+)
+
+func TestInsertSVG(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rect.svg")
+	src := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10"><rect width="20" height="10" fill="#0000ff"/></svg>`
+	if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{})
+	if err := svg.InsertSVG(pack, 0, filename, 8, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	sprite := pack.SubImage(0)
+	if b := sprite.Bounds(); b.Dx() != 8 || b.Dy() != 4 {
+		t.Fatalf("expected an 8x4 sprite, got %v", b)
+	}
+	blue := color.RGBA{0, 0, 255, 255}
+	for y := sprite.Bounds().Min.Y; y < sprite.Bounds().Max.Y; y++ {
+		for x := sprite.Bounds().Min.X; x < sprite.Bounds().Max.X; x++ {
+			if c := sprite.RGBAAt(x, y); c != blue {
+				t.Fatalf("expected %v at (%d, %d), got %v", blue, x, y, c)
+			}
+		}
+	}
+}
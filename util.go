@@ -1,7 +1,16 @@
 package rectpack
 
 import (
+	"encoding/binary"
+	"hash/crc32"
 	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
 )
 
 type queuedData struct {
@@ -9,6 +18,7 @@ type queuedData struct {
 	pic *image.RGBA
 }
 
+
 // container for the leftover space after split
 type createdSplits struct {
 	hasSmall, hasBig bool
@@ -32,17 +42,170 @@ func splits(rects ...image.Rectangle) (s *createdSplits) {
 	return
 }
 
+// helper to create a horizontally and/or vertically mirrored copy of the picture
+func flip(pic *image.RGBA, h, v bool) (flipped *image.RGBA) {
+	r := pic.Bounds()
+	flipped = image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			sx, sy := x, y
+			if h {
+				sx = r.Dx() - 1 - x
+			}
+			if v {
+				sy = r.Dy() - 1 - y
+			}
+			flipped.SetRGBA(x, y, pic.RGBAAt(r.Min.X+sx, r.Min.Y+sy))
+		}
+	}
+	return
+}
+
+// helper to create a copy of the picture rotated 90 degrees clockwise
+func rotate(pic *image.RGBA) (rotated *image.RGBA) {
+	r := pic.Bounds()
+	rotated = image.NewRGBA(image.Rect(0, 0, r.Dy(), r.Dx()))
+	for y := 0; y < r.Dx(); y++ {
+		for x := 0; x < r.Dy(); x++ {
+			rotated.SetRGBA(x, y, pic.RGBAAt(r.Min.X+y, r.Max.Y-1-x))
+		}
+	}
+	return
+}
+
+// helper to create a copy of the picture rotated 90 degrees counter clockwise, undoing rotate
+func unrotate(pic *image.RGBA) (upright *image.RGBA) {
+	r := pic.Bounds()
+	upright = image.NewRGBA(image.Rect(0, 0, r.Dy(), r.Dx()))
+	for y := 0; y < r.Dx(); y++ {
+		for x := 0; x < r.Dy(); x++ {
+			upright.SetRGBA(x, y, pic.RGBAAt(r.Max.X-1-y, r.Min.Y+x))
+		}
+	}
+	return
+}
+
+// helper to map a rectangle in a picture of width w to where unrotate moves it
+func unrotateRect(r image.Rectangle, w int) image.Rectangle {
+	return image.Rect(r.Min.Y, w-r.Max.X, r.Max.Y, w-r.Min.X)
+}
+
+// helper to shrink the picture to half its size, averaging each 2x2 block of pixels
+func halve(pic *image.RGBA) (half *image.RGBA) {
+	r := pic.Bounds()
+	w, h := (r.Dx()+1)/2, (r.Dy()+1)/2
+	half = image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum [4]int
+			n := 0
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					sx, sy := r.Min.X+2*x+dx, r.Min.Y+2*y+dy
+					if sx >= r.Max.X || sy >= r.Max.Y {
+						continue
+					}
+					c := pic.RGBAAt(sx, sy)
+					sum[0] += int(c.R)
+					sum[1] += int(c.G)
+					sum[2] += int(c.B)
+					sum[3] += int(c.A)
+					n++
+				}
+			}
+			half.SetRGBA(x, y, color.RGBA{R: uint8(sum[0] / n), G: uint8(sum[1] / n), B: uint8(sum[2] / n), A: uint8(sum[3] / n)})
+		}
+	}
+	return
+}
+
+// helper to find the smallest rectangle holding every pixel with an alpha above threshold that isn't the matte color, if given.
+// A picture with no such pixel keeps its top left pixel, so blank frames still get a place in the atlas.
+func opaqueBounds(pic *image.RGBA, threshold uint8, matte *color.RGBA) (bounds image.Rectangle) {
+	r := pic.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := pic.RGBAAt(x, y); c.A > threshold && (matte == nil || c != *matte) {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if bounds.Empty() {
+		return image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Min.Y+1).Intersect(r)
+	}
+	return
+}
+
 // helper function to create rectangles
 func rect(x, y, w, h int) image.Rectangle {
 	return image.Rect(x, y, x+w, y+h)
 }
 
+// helper to join two rectangles that share a full edge into one
+func join(a, b image.Rectangle) (r image.Rectangle, ok bool) {
+	if a.Min.X == b.Min.X && a.Max.X == b.Max.X && (a.Max.Y == b.Min.Y || b.Max.Y == a.Min.Y) {
+		return a.Union(b), true
+	}
+	if a.Min.Y == b.Min.Y && a.Max.Y == b.Max.Y && (a.Max.X == b.Min.X || b.Max.X == a.Min.X) {
+		return a.Union(b), true
+	}
+	return
+}
+
 func area(r image.Rectangle) int {
 	return r.Dx() * r.Dy()
 }
 
+// length of the longest side
+func maxSide(r image.Rectangle) int {
+	if r.Dx() > r.Dy() {
+		return r.Dx()
+	}
+	return r.Dy()
+}
+
+// ratio of the longest side to the shortest side
+func aspect(r image.Rectangle) float64 {
+	long, short := r.Dx(), r.Dy()
+	if short > long {
+		long, short = short, long
+	}
+	if short == 0 {
+		return float64(long)
+	}
+	return float64(long) / float64(short)
+}
+
+// helper to grow size in a single direction to fit growBy, picking the direction that ends closest to the target ratio.
+// Both directions are kept within limit first, a direction that can't grow at all is only picked if neither can.
+func growTowards(size, growBy image.Point, target float64, limit func(image.Point) image.Point) image.Point {
+	wider := image.Pt(size.X+growBy.X, size.Y)
+	if wider.Y < growBy.Y {
+		wider.Y = growBy.Y
+	}
+	taller := image.Pt(size.X, size.Y+growBy.Y)
+	if taller.X < growBy.X {
+		taller.X = growBy.X
+	}
+	wider, taller = limit(wider), limit(taller)
+	if wider == size {
+		return taller
+	}
+	if taller == size {
+		return wider
+	}
+
+	distance := func(p image.Point) float64 {
+		return math.Abs(math.Log(float64(p.X) / float64(p.Y) / target))
+	}
+	if distance(wider) <= distance(taller) {
+		return wider
+	}
+	return taller
+}
+
 // helper to split existing space
-func split(img, space image.Rectangle) (s *createdSplits, err error) {
+func split(img, space image.Rectangle, strategy SplitStrategy) (s *createdSplits, err error) {
 	w := space.Dx() - img.Dx()
 	h := space.Dy() - img.Dy()
 
@@ -59,8 +222,19 @@ func split(img, space image.Rectangle) (s *createdSplits, err error) {
 		return splits(r), nil
 	}
 
+	var vertical bool
+	switch strategy {
+	case LongerAxis:
+		vertical = w <= h
+	case MaximizeLargestRect:
+		vertical = w*space.Dy() > space.Dx()*h
+	default:
+		vertical = w > h
+	}
+
+	// a vertical cut gives the space right of the image the full height, otherwise the space below gets the full width
 	var smaller, larger image.Rectangle
-	if w > h {
+	if vertical {
 		smaller = rect(space.Min.X, space.Min.Y+img.Dy(), img.Dx(), h)
 		larger = rect(space.Min.X+img.Dx(), space.Min.Y, w, space.Dy())
 	} else {
@@ -70,3 +244,196 @@ func split(img, space image.Rectangle) (s *createdSplits, err error) {
 
 	return splits(smaller, larger), nil
 }
+
+// helper to reinterpret the premultiplied pixels of img as straight alpha so that encoders store them as-is
+func premultiplied(img *image.RGBA) *image.NRGBA {
+	pix := make([]uint8, len(img.Pix))
+	copy(pix, img.Pix)
+	return &image.NRGBA{
+		Pix:    pix,
+		Stride: img.Stride,
+		Rect:   img.Rect,
+	}
+}
+
+// helper to widen the premultiplied pixels of img to 16 bits and reinterpret them as straight alpha, like premultiplied
+func premultiplied64(img *image.RGBA) *image.NRGBA64 {
+	pix := make([]uint8, 2*len(img.Pix))
+	for i, v := range img.Pix {
+		pix[2*i], pix[2*i+1] = v, v
+	}
+	return &image.NRGBA64{
+		Pix:    pix,
+		Stride: 2 * img.Stride,
+		Rect:   img.Rect,
+	}
+}
+
+// helper to write a file next to filename and move it into place once write succeeds, so a failure never leaves a partial file
+func writeAtomic(filename string, write func(io.Writer) error) (err error) {
+	var file *os.File
+	if file, err = os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp"); err != nil {
+		return
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(file.Name(), filename)
+		}
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+
+	// temp files are created 0600, keep the permissions of the file being replaced or use 0644 for a new one (the umask isn't applied)
+	mode := os.FileMode(0644)
+	if info, serr := os.Stat(filename); serr == nil {
+		mode = info.Mode().Perm()
+	}
+	if err = file.Chmod(mode); err != nil {
+		return
+	}
+	if err = write(file); err != nil {
+		return
+	}
+	// flush to disk before the rename so a crash can't leave an empty file in place of the old one
+	return file.Sync()
+}
+
+// helper to collect every distinct color in the picture
+func uniqueColors(pic *image.RGBA) (colors []color.RGBA) {
+	seen := make(map[color.RGBA]bool)
+	for i := 0; i+3 < len(pic.Pix); i += 4 {
+		c := color.RGBA{R: pic.Pix[i], G: pic.Pix[i+1], B: pic.Pix[i+2], A: pic.Pix[i+3]}
+		if !seen[c] {
+			seen[c] = true
+			colors = append(colors, c)
+		}
+	}
+	return
+}
+
+// helper to reduce colors to at most n colors by repeatedly splitting the box with the widest channel range at its median
+func medianCut(colors []color.RGBA, n int) (palette color.Palette) {
+	channel := func(c color.RGBA, ch int) uint8 {
+		return [4]uint8{c.R, c.G, c.B, c.A}[ch]
+	}
+	widest := func(box []color.RGBA) (ch int, spread int) {
+		for i := 0; i < 4; i++ {
+			lo, hi := 255, 0
+			for _, c := range box {
+				v := int(channel(c, i))
+				if v < lo {
+					lo = v
+				}
+				if v > hi {
+					hi = v
+				}
+			}
+			if hi-lo > spread {
+				ch, spread = i, hi-lo
+			}
+		}
+		return
+	}
+
+	boxes := [][]color.RGBA{colors}
+	for len(boxes) < n {
+		best, bestCh, bestSpread := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if ch, spread := widest(box); spread > bestSpread {
+				best, bestCh, bestSpread = i, ch, spread
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool {
+			return channel(box[i], bestCh) < channel(box[j], bestCh)
+		})
+		boxes[best] = box[:len(box)/2]
+		boxes = append(boxes, box[len(box)/2:])
+	}
+
+	for _, box := range boxes {
+		var sum [4]int
+		for _, c := range box {
+			sum[0] += int(c.R)
+			sum[1] += int(c.G)
+			sum[2] += int(c.B)
+			sum[3] += int(c.A)
+		}
+		l := len(box)
+		palette = append(palette, color.RGBA{R: uint8(sum[0] / l), G: uint8(sum[1] / l), B: uint8(sum[2] / l), A: uint8(sum[3] / l)})
+	}
+	return
+}
+
+// helper to convert the picture to a paletted image of its colors, reduced to at most n with median cut
+func paletted(pic *image.RGBA, colors []color.RGBA, n int) *image.Paletted {
+	palette := make(color.Palette, 0, len(colors))
+	if len(colors) > n {
+		palette = medianCut(colors, n)
+	} else {
+		for _, c := range colors {
+			palette = append(palette, c)
+		}
+	}
+
+	img := image.NewPaletted(pic.Bounds(), palette)
+	draw.Draw(img, img.Bounds(), pic, pic.Bounds().Min, draw.Src)
+	return img
+}
+
+// length and type of the signature and IHDR chunk that every png starts with, the pHYs chunk goes right after them
+const pngHeaderLen = 8 + 4 + 4 + 13 + 4
+
+// writer that inserts a png pHYs chunk with the given density after the png header passes through
+type physWriter struct {
+	w     io.Writer
+	n     int
+	chunk []byte
+}
+
+func newPhysWriter(w io.Writer, dpi float64) *physWriter {
+	ppm := uint32(math.Round(dpi / 0.0254))
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk, 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	// the unit is the meter
+	chunk[16] = 1
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+	return &physWriter{w: w, chunk: chunk}
+}
+
+func (pw *physWriter) Write(p []byte) (n int, err error) {
+	if pw.n < pngHeaderLen {
+		head := p
+		if len(head) > pngHeaderLen-pw.n {
+			head = head[:pngHeaderLen-pw.n]
+		}
+		if n, err = pw.w.Write(head); err != nil {
+			return
+		}
+		pw.n += n
+		p = p[n:]
+		if pw.n < pngHeaderLen {
+			return
+		}
+		if _, err = pw.w.Write(pw.chunk); err != nil {
+			return
+		}
+	}
+
+	m, err := pw.w.Write(p)
+	return n + m, err
+}
@@ -0,0 +1,64 @@
+// Package watch repacks an atlas whenever the files its sprites were inserted from change on disk
+package watch
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/dusk125/rectpack"
+)
+
+// Polls the files returned by the packer's SourceFiles every WatchInterval and, whenever one of them changes,
+// reads them all into a new packer with Reload and passes it to onRepack along with any error.
+// Watch blocks until ctx is done.
+func Watch(ctx context.Context, pack *rectpack.Packer, onRepack func(*rectpack.Packer, error)) {
+	interval := pack.Config().WatchInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	files := pack.SourceFiles()
+	last := make(map[int]time.Time, len(files))
+	for id, file := range files {
+		last[id] = file.ModTime
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := modTimes(files)
+		changed := false
+		for id, mod := range current {
+			if !mod.Equal(last[id]) {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			continue
+		}
+		last = current
+
+		onRepack(pack.Reload())
+	}
+}
+
+// Helper to get the modification time of every watched file, missing files get the zero time
+func modTimes(files map[int]rectpack.SourceFile) (times map[int]time.Time) {
+	times = make(map[int]time.Time, len(files))
+	for id, file := range files {
+		if info, err := os.Stat(file.Filename); err == nil {
+			times[id] = info.ModTime()
+		} else {
+			times[id] = time.Time{}
+		}
+	}
+	return
+}
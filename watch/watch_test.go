@@ -0,0 +1,104 @@
+package watch_test
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dusk125/rectpack"
+	"github.com/dusk125/rectpack/watch"
+)
+
+func writePNG(t *testing.T, filename string, c color.RGBA) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err = png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		red   = color.RGBA{R: 255, A: 255}
+		blue  = color.RGBA{B: 255, A: 255}
+		files = []string{filepath.Join(dir, "sprite.png"), filepath.Join(dir, "sprite.dat")}
+	)
+	for _, filename := range files {
+		writePNG(t, filename, red)
+	}
+
+	pack := rectpack.NewPacker(rectpack.PackerCfg{WatchInterval: 10 * time.Millisecond})
+	if err := pack.InsertFromFile(0, files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.InsertFromFileFormat(1, files[1], "png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pack.Pack(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repacked := make(chan *rectpack.Packer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watch.Watch(ctx, pack, func(p *rectpack.Packer, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			select {
+			case repacked <- p:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	later := time.Now().Add(time.Minute)
+	for _, filename := range files {
+		writePNG(t, filename, blue)
+		if err := os.Chtimes(filename, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the files may be seen changing one at a time, so wait for a repack with both
+	timeout := time.After(5 * time.Second)
+wait:
+	for {
+		select {
+		case p := <-repacked:
+			updated := true
+			for id := range files {
+				r := p.Get(id)
+				updated = updated && p.Image().RGBAAt(r.Min.X, r.Min.Y) == blue
+			}
+			if updated {
+				break wait
+			}
+		case <-timeout:
+			t.Error("Watch didn't repack with both changed files")
+			break wait
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Watch didn't stop after the context was cancelled")
+	}
+}